@@ -24,8 +24,10 @@ type App struct {
 	git http.Handler
 }
 
-// NewApp creates a new app and injects dependencies into graph
-func NewApp(config *Config) (*App, error) {
+// NewApp creates a new app and injects dependencies into graph, additional
+// values such as a *Renderers registry are provided to the graph so embedding
+// programs can extend the blog
+func NewApp(config *Config, values ...interface{}) (*App, error) {
 	var graph inject.Graph
 	var app App
 
@@ -48,6 +50,12 @@ func NewApp(config *Config) (*App, error) {
 		return nil, err
 	}
 
+	for _, value := range values {
+		if err := graph.Provide(&inject.Object{Value: value}); err != nil {
+			return nil, err
+		}
+	}
+
 	if err := graph.Populate(); err != nil {
 		return nil, err
 	}
@@ -64,8 +72,7 @@ func (a *App) Error(ctx context.Context, w http.ResponseWriter, r *http.Request,
 		WithField("status", status).
 		Error("Handler encountered an error")
 
-	w.WriteHeader(status)
-	w.Write([]byte("Error!"))
+	a.Blog.ErrorPage(ctx, w, r, status, err)
 }
 
 // NotFound is a not found handler
@@ -114,6 +121,7 @@ func (a *App) LogMetricsMiddleware(next scaffold.Handler) scaffold.Handler {
 		log = log.WithField("request_id", rid)
 
 		ctx = StoreLog(ctx, log)
+		ctx = StoreRequestID(ctx, rid)
 
 		log.
 			WithField("start", start).
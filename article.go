@@ -2,34 +2,162 @@ package blog
 
 import (
 	"fmt"
+	"html"
 	"html/template"
 	"net/url"
+	"path"
+	"strconv"
 	"strings"
 	"time"
 
-	"github.com/kennygrant/sanitize"
+	"github.com/gogits/git"
 )
 
 // Article contains information on an article
 type Article struct {
-	Name string
-	Mod  time.Time
-	Data []byte
+	Name       string
+	Path       string
+	Section    string
+	Title      string
+	Summary    string
+	Layout     string
+	Tags       []string
+	Categories []string
+	Draft      bool
+	Weight     int
+	Language   string
+	Date       time.Time
+	Mod        time.Time
+	Data       []byte
+	Excerpt    []byte
+	More       bool
+	TOC        TOC
+
+	Author       *Author
+	Contributors []*Author
+	Backlinks    Index
+	Translations Translations
+
+	base   *url.URL
+	prefix string
+}
+
+// ApplyFrontMatter sets the article metadata from front matter, missing values
+// fall back to the file name and commit time. Dates without an offset are
+// read in loc
+func (a *Article) ApplyFrontMatter(meta FrontMatter, loc *time.Location) {
+	if slug := meta.String("slug"); slug != "" {
+		a.Name = path.Join(a.Section, slug)
+	}
+
+	a.Title = meta.String("title")
+	a.Summary = meta.String("summary")
+	a.Layout = meta.String("layout")
+	a.Tags = meta.Strings("tags")
+	a.Categories = meta.Strings("categories")
+	if len(a.Categories) == 0 {
+		a.Categories = meta.Strings("category")
+	}
+	a.Draft = meta.Bool("draft")
+	a.Weight, _ = strconv.Atoi(meta.String("weight"))
+
+	if author := meta.String("author"); author != "" {
+		a.Author = &Author{ID: authorID(author, ""), Name: author}
+	}
+
+	if date, ok := meta.TimeIn("date", loc); ok {
+		a.Date = date
+	}
+
+	if a.Title == "" {
+		a.Title = a.Name
+	}
+
+	if a.Date.IsZero() {
+		a.Date = a.Mod
+	}
+}
+
+// ApplyHistory sets the modification time, author and contributors from the
+// commits that changed the article, newest first
+func (a *Article) ApplyHistory(history []*git.Commit) {
+	if len(history) == 0 {
+		return
+	}
+
+	a.Mod = commitTime(history[0])
+
+	seen := make(map[string]bool)
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].Author == nil {
+			continue
+		}
+
+		author := NewAuthor(history[i].Author)
+		if seen[author.ID] {
+			continue
+		}
+		seen[author.ID] = true
+
+		if a.Author == nil {
+			a.Author = author
+		}
+		a.Contributors = append(a.Contributors, author)
+	}
+}
+
+// Published checks if the article is visible at the given time, drafts and
+// articles dated in the future are not published
+func (a *Article) Published(now time.Time) bool {
+	return !a.Draft && !a.Date.After(now)
+}
+
+// Sections lists the section and all parent sections the article is in, for
+// example go/tips/article is in both go and go/tips
+func (a *Article) Sections() []string {
+	if a.Section == "" {
+		return nil
+	}
+
+	parts := strings.Split(a.Section, "/")
+	sections := make([]string, len(parts))
+	for i := range parts {
+		sections[i] = strings.Join(parts[:i+1], "/")
+	}
+
+	return sections
 }
 
 // Preview generates a preview for the article listing
 func (a *Article) Preview(baseURL *url.URL) template.HTML {
-	data, _ := sanitize.HTMLAllowing(string(a.Data), []string{"h1"})
-	data = strings.Replace(data, "<h1>", "<h3>", -1)
-	data = strings.Replace(data, "</h1>", "</h3>", -1)
-	if len(data) > 500 {
-		url, _ := baseURL.Parse("article/" + a.Name)
-		data = fmt.Sprintf(`%s... <a href="%s">(Read more)</a>`, data[:500], url.String())
+	data := expandBaseURL(a.Excerpt, baseURL)
+	more := a.More
+	if a.Summary != "" {
+		data = "<p>" + html.EscapeString(a.Summary) + "</p>"
+		more = true
+	}
+	if more {
+		url, _ := baseURL.Parse(a.Link())
+		data = fmt.Sprintf(`%s... <a href="%s">(Read more)</a>`, data, url.String())
 	}
 	return template.HTML(data)
 }
 
-// Full returns the full article
+// Link is the path of the article under the base url, translations are
+// served under their language, for example fr/article/name/
+func (a *Article) Link() string {
+	return a.prefix + "article/" + a.Name + "/"
+}
+
+// Full returns the full article, links are relative to the base url set by
+// WithBaseURL
 func (a *Article) Full() template.HTML {
-	return template.HTML(a.Data)
+	return template.HTML(expandBaseURL(a.Data, a.base))
+}
+
+// WithBaseURL returns a copy of the article that links relative to baseURL
+func (a *Article) WithBaseURL(baseURL *url.URL) *Article {
+	article := *a
+	article.base = baseURL
+	return &article
 }
@@ -0,0 +1,91 @@
+package blog
+
+import (
+	"html/template"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gogits/git"
+)
+
+// Author is someone who wrote or contributed to an article
+type Author struct {
+	ID    string
+	Name  string
+	Email string
+}
+
+// NewAuthor creates an author from a git signature. Authors are identified by
+// the slug of their name, so people sharing a name share an author page
+func NewAuthor(signature *git.Signature) *Author {
+	return &Author{
+		ID:    authorID(signature.Name, signature.Email),
+		Name:  signature.Name,
+		Email: signature.Email,
+	}
+}
+
+// authorID is the slug of an author name, names without letters or digits
+// fall back to the local part of the email and then to anonymous
+func authorID(name string, email string) string {
+	if id := Slugify(name); id != "" {
+		return id
+	}
+
+	if i := strings.LastIndex(email, "@"); i >= 0 {
+		email = email[:i]
+	}
+	if id := Slugify(email); id != "" {
+		return id
+	}
+
+	return "anonymous"
+}
+
+func (a *Author) String() string {
+	return a.Name
+}
+
+// AuthorPage contains the articles written by an author and their bio
+type AuthorPage struct {
+	Author        *Author
+	Bio           []byte
+	Articles      Index
+	Contributions Index
+
+	base *url.URL
+}
+
+// Published filters the author page to articles published at the given time
+func (p *AuthorPage) Published(now time.Time) *AuthorPage {
+	return &AuthorPage{
+		Author:        p.Author,
+		Bio:           p.Bio,
+		Articles:      p.Articles.Published(now),
+		Contributions: p.Contributions.Published(now),
+	}
+}
+
+// FullBio returns the rendered bio
+func (p *AuthorPage) FullBio() template.HTML {
+	return template.HTML(expandBaseURL(p.Bio, p.base))
+}
+
+// WithBaseURL returns a copy of the page with a bio that links relative to
+// baseURL
+func (p *AuthorPage) WithBaseURL(baseURL *url.URL) *AuthorPage {
+	page := *p
+	page.base = baseURL
+	return &page
+}
+
+func commitTime(commit *git.Commit) time.Time {
+	if commit.Committer != nil {
+		return commit.Committer.When
+	}
+	if commit.Author != nil {
+		return commit.Author.When
+	}
+	return time.Time{}
+}
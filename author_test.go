@@ -0,0 +1,33 @@
+package blog
+
+import (
+	"testing"
+
+	"github.com/gogits/git"
+)
+
+func TestNewAuthor(t *testing.T) {
+	tests := []struct {
+		name  string
+		email string
+		id    string
+	}{
+		{"Adam Talbot", "adam@example.com", "adam-talbot"},
+		{"Zoë Ñoño", "zoe@example.com", "zoë-ñoño"},
+		{"???", "first.last@example.com", "first-last"},
+		{"", "me@example.com", "me"},
+		{"***", "", "anonymous"},
+		{"!!", "@example.com", "anonymous"},
+	}
+
+	for _, test := range tests {
+		author := NewAuthor(&git.Signature{Name: test.name, Email: test.email})
+
+		if author.ID != test.id {
+			t.Errorf("%q <%s>: expected id %q, got %q", test.name, test.email, test.id, author.ID)
+		}
+		if author.Name != test.name || author.Email != test.email {
+			t.Errorf("%q <%s>: expected the signature to be kept, got %q <%s>", test.name, test.email, author.Name, author.Email)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package blog
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"mime"
@@ -9,7 +10,9 @@ import (
 	"net/url"
 	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/ThatsMrTalbot/scaffold"
 	"github.com/ThatsMrTalbot/scaffold/errors"
@@ -24,25 +27,120 @@ type Blog struct {
 	Cache  *Cache          `inject:""`
 }
 
-// IndexModel creates an IndexModel for use in the index template
-func (b *Blog) IndexModel(ctx context.Context, r *http.Request, index Index) *IndexModel {
+// IndexModel creates an IndexModel for use in the index template, lang is
+// the language of the index or empty for the default language
+func (b *Blog) IndexModel(ctx context.Context, r *http.Request, index Index, lang string) *IndexModel {
 	page, _ := scaffold.GetParam(ctx, "page").Int()
 
-	return &IndexModel{
-		Page:     page,
-		Count:    index.Pages(20),
-		Articles: index.Page(page, 20),
+	model := b.listModel(ctx, r, index, page, b.site(ctx).languagePrefix(lang))
+	if lang != "" {
+		model.Language = lang
+	}
+
+	return model
+}
+
+// SectionModel creates a SectionModel for use in the section template
+func (b *Blog) SectionModel(ctx context.Context, r *http.Request, section string, index Index, page int) *SectionModel {
+	return &SectionModel{
+		IndexModel: b.listModel(ctx, r, index, page, "section/"+section+"/"),
+		Section:    section,
+	}
+}
+
+// TagsModel creates a TagsModel for use in the tags template
+func (b *Blog) TagsModel(ctx context.Context, r *http.Request, name string, taxonomy Taxonomy) *TagsModel {
+	return &TagsModel{
+		Taxonomy: name,
+		Tags:     taxonomy.Terms(),
 		BaseURL:  b.BaseURL(ctx, r),
-		GitURL:   b.GitURL(r),
+		GitURL:   b.GitURL(ctx, r),
+		Site:     b.site(ctx),
+	}
+}
+
+// TagModel creates a TagModel for use in the tag template
+func (b *Blog) TagModel(ctx context.Context, r *http.Request, name string, term *Term) *TagModel {
+	page, _ := scaffold.GetParam(ctx, "page").Int()
+
+	return &TagModel{
+		IndexModel: b.listModel(ctx, r, term.Articles, page, name+"/"+term.Slug+"/"),
+		Taxonomy:   name,
+		Tag:        term,
+	}
+}
+
+// AuthorModel creates an AuthorModel for use in the author template
+func (b *Blog) AuthorModel(ctx context.Context, r *http.Request, author *AuthorPage) *AuthorModel {
+	page, _ := scaffold.GetParam(ctx, "page").Int()
+
+	return &AuthorModel{
+		IndexModel: b.listModel(ctx, r, author.Articles, page, "authors/"+author.Author.ID+"/"),
+		Author:     author.WithBaseURL(b.BaseURL(ctx, r)),
+	}
+}
+
+func (b *Blog) listModel(ctx context.Context, r *http.Request, index Index, page int, path string) *IndexModel {
+	site := b.site(ctx)
+
+	return &IndexModel{
+		Page:        page,
+		Count:       index.Pages(site.PageSize),
+		Articles:    index.Page(page, site.PageSize),
+		BaseURL:     b.BaseURL(ctx, r),
+		GitURL:      b.GitURL(ctx, r),
+		Site:        site,
+		Language:    site.Language,
+		Path:        path,
+		BrokenLinks: b.brokenLinks(ctx),
+		Diagnostics: b.diagnostics(ctx),
 	}
 }
 
 // ArticleModel creates an ArticleModel for use in the article tempalte
 func (b *Blog) ArticleModel(ctx context.Context, r *http.Request, article *Article) *ArticleModel {
+	baseURL := b.BaseURL(ctx, r)
+
+	article = article.WithBaseURL(baseURL)
+	if !b.Preview(ctx) {
+		article.Backlinks = article.Backlinks.Published(time.Now())
+		article.Translations = article.Translations.Published(time.Now())
+	}
+
+	// Alternates list every variant of the article, including itself, for
+	// hreflang links
+	variants := []Translation{{Language: article.Language, Title: article.Title, Link: article.Link()}}
+	variants = append(variants, article.Translations...)
+
+	alternates := make([]Translation, len(variants))
+	for i, t := range variants {
+		t.Link, _ = resolveURL(baseURL, t.Link)
+		alternates[i] = t
+	}
+
 	return &ArticleModel{
-		Article: article,
-		BaseURL: b.BaseURL(ctx, r),
-		GitURL:  b.GitURL(r),
+		Article:      article,
+		BaseURL:      baseURL,
+		GitURL:       b.GitURL(ctx, r),
+		Site:         b.site(ctx),
+		Translations: alternates[1:],
+		Alternates:   alternates,
+		BrokenLinks:  b.brokenLinks(ctx).For(article.Path),
+		Diagnostics:  b.diagnostics(ctx),
+	}
+}
+
+// PageModel creates a PageModel for use in the page template
+func (b *Blog) PageModel(ctx context.Context, r *http.Request, page *Article) *PageModel {
+	baseURL := b.BaseURL(ctx, r)
+
+	return &PageModel{
+		Page:        page.WithBaseURL(baseURL),
+		BaseURL:     baseURL,
+		GitURL:      b.GitURL(ctx, r),
+		Site:        b.site(ctx),
+		BrokenLinks: b.brokenLinks(ctx).For(page.Path),
+		Diagnostics: b.diagnostics(ctx),
 	}
 }
 
@@ -52,19 +150,39 @@ func (b *Blog) Index(ctx context.Context, w http.ResponseWriter, r *http.Request
 
 	log.Info("Index handler called")
 
+	lang, ok := b.language(ctx)
+	if !ok {
+		return errors.NewErrorStatus(404, "Language not found")
+	}
+
+	if page, _ := scaffold.GetParam(ctx, "page").Int(); page < 0 {
+		return errors.NewErrorStatus(404, "Page not found")
+	}
+
+	return b.index(ctx, w, r, lang)
+}
+
+// index renders the index of a language, the default language is empty
+func (b *Blog) index(ctx context.Context, w http.ResponseWriter, r *http.Request, lang string) error {
+	log := GetLog(ctx)
+
 	tid, id, err := b.getID(ctx, b.Repo)
 	if err != nil {
 		return ErrorReponse(500, "Could not get commit id", err)
 	}
 
-	index, ok := b.Cache.GetIndex(tid, id)
+	index, ok := b.Cache.GetLanguageIndex(tid, id, lang)
 	if !ok {
 		return errors.NewErrorStatus(404, "Index not found")
 	}
 
+	if !b.Preview(ctx) {
+		index = index.Published(time.Now())
+	}
+
 	log.Info("Loaded index from cache")
 
-	model := b.IndexModel(ctx, r, index)
+	model := b.IndexModel(ctx, r, index, lang)
 
 	var buffer bytes.Buffer
 	err = b.Cache.GetIndexTemplate(tid, id).Execute(&buffer, model)
@@ -89,10 +207,8 @@ func (b *Blog) Article(ctx context.Context, w http.ResponseWriter, r *http.Reque
 		return ErrorReponse(500, "Could not get commit id", err)
 	}
 
-	name, _ := scaffold.GetParam(ctx, "article").String()
-
-	article, ok := b.Cache.GetArticle(tid, id, name)
-	if !ok {
+	article, rest, ok := b.resolveArticle(ctx, r, tid, id)
+	if !ok || rest != "" || !(b.Preview(ctx) || article.Published(time.Now())) {
 		return errors.NewErrorStatus(404, "Article not found")
 	}
 
@@ -112,6 +228,689 @@ func (b *Blog) Article(ctx context.Context, w http.ResponseWriter, r *http.Reque
 	return nil
 }
 
+// Page is the page handler, pages are read from the pages directory of the
+// tree and served from the root of the site
+func (b *Blog) Page(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	log := GetLog(ctx)
+
+	log.Info("Page handler called")
+
+	tid, id, err := b.getID(ctx, b.Repo)
+	if err != nil {
+		return ErrorReponse(500, "Could not get commit id", err)
+	}
+
+	name, _ := scaffold.GetParam(ctx, "page").String()
+
+	// The index of a language shares the route of pages, such as /fr/
+	if b.site(ctx).HasLanguage(name) {
+		return b.index(ctx, w, r, name)
+	}
+
+	page, ok := b.Cache.GetPage(tid, id, name)
+	if !ok || !(b.Preview(ctx) || page.Published(time.Now())) {
+		return errors.NewErrorStatus(404, "Page not found")
+	}
+
+	log.Info("Loaded page from cache")
+
+	model := b.PageModel(ctx, r, page)
+
+	var buffer bytes.Buffer
+	err = b.Cache.GetTemplate(tid, id, "page.tpl").Execute(&buffer, model)
+	if err != nil {
+		return ErrorReponse(500, "Could not execute page template", err)
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	w.Write(buffer.Bytes())
+
+	return nil
+}
+
+// Links is the broken link report handler, the report is served as json
+func (b *Blog) Links(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	log := GetLog(ctx)
+
+	log.Info("Links handler called")
+
+	tid, id, err := b.getID(ctx, b.Repo)
+	if err != nil {
+		return ErrorReponse(500, "Could not get commit id", err)
+	}
+
+	report, ok := b.Cache.GetLinkReport(tid, id)
+	if !ok {
+		return errors.NewErrorStatus(404, "Link report not found")
+	}
+
+	if report == nil {
+		report = LinkReport{}
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return ErrorReponse(500, "Could not encode link report", err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+
+	return nil
+}
+
+// BuildReport is the build handler, it lists the problems found while
+// building the commit and its broken links
+func (b *Blog) BuildReport(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	log := GetLog(ctx)
+
+	log.Info("Build handler called")
+
+	tid, id, err := b.getID(ctx, b.Repo)
+	if err != nil {
+		return ErrorReponse(500, "Could not get commit id", err)
+	}
+
+	diagnostics, ok := b.Cache.GetDiagnostics(tid, id)
+	if !ok {
+		return errors.NewErrorStatus(404, "Build report not found")
+	}
+
+	links, _ := b.Cache.GetLinkReport(tid, id)
+
+	model := &BuildModel{
+		GitURL:      b.GitURL(ctx, r),
+		BaseURL:     b.BaseURL(ctx, r),
+		Site:        b.site(ctx),
+		Commit:      id,
+		Diagnostics: diagnostics,
+		BrokenLinks: links,
+	}
+
+	var buffer bytes.Buffer
+	err = b.Cache.GetTemplate(tid, id, "build.tpl").Execute(&buffer, model)
+	if err != nil {
+		return ErrorReponse(500, "Could not execute build template", err)
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	w.Write(buffer.Bytes())
+
+	return nil
+}
+
+// ErrorPage renders an error with the 404.tpl or error.tpl template of the
+// tree being served, clients that prefer json get a problem detail instead
+func (b *Blog) ErrorPage(ctx context.Context, w http.ResponseWriter, r *http.Request, status int, err error) {
+	log := GetLog(ctx)
+
+	model := &ErrorModel{
+		GitURL:     b.GitURL(ctx, r),
+		BaseURL:    b.BaseURL(ctx, r),
+		Site:       b.site(ctx),
+		Status:     status,
+		StatusText: http.StatusText(status),
+		Message:    errorMessage(status, err),
+		RequestID:  GetRequestID(ctx, r),
+	}
+
+	if prefersJSON(r) {
+		data, err := json.Marshal(&Problem{
+			Type:      "about:blank",
+			Title:     model.StatusText,
+			Status:    status,
+			Detail:    model.Message,
+			Instance:  r.URL.Path,
+			RequestID: model.RequestID,
+		})
+		if err != nil {
+			log.WithError(err).Error("Could not encode problem")
+			http.Error(w, model.StatusText, status)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/problem+json")
+		w.WriteHeader(status)
+		w.Write(data)
+		return
+	}
+
+	name := "error.tpl"
+	if status == 404 {
+		name = "404.tpl"
+	}
+
+	tpl := defaultTemplates[name]
+	if tid, id, err := b.getID(ctx, b.Repo); err == nil {
+		tpl = b.Cache.GetTemplate(tid, id, name)
+	}
+
+	var buffer bytes.Buffer
+	if err := tpl.Execute(&buffer, model); err != nil {
+		log.WithError(err).Warn("Could not execute error template, using default")
+
+		buffer.Reset()
+		if err := defaultTemplates[name].Execute(&buffer, model); err != nil {
+			log.WithError(err).Error("Could not execute default error template")
+			http.Error(w, model.StatusText, status)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	w.WriteHeader(status)
+	w.Write(buffer.Bytes())
+}
+
+// Image is the image handler, images from the tree are resized to one of
+// ImageWidths
+func (b *Blog) Image(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	log := GetLog(ctx)
+
+	log.Info("Image handler called")
+
+	width, err := scaffold.GetParam(ctx, "width").Int()
+	if err != nil {
+		return errors.NewErrorStatus(404, "Image not found")
+	}
+
+	tid, id, err := b.getID(ctx, b.Repo)
+	if err != nil {
+		return ErrorReponse(500, "Could not get commit id", err)
+	}
+
+	name := b.pathParam(ctx, r, "_img/"+strconv.Itoa(width))
+
+	img, ok := b.Cache.GetImage(tid, id, name, width)
+	if !ok {
+		return errors.NewErrorStatus(404, "Image not found")
+	}
+
+	log.
+		WithField("filepath", name).
+		WithField("width", width).
+		Info("Serving resized image")
+
+	// The url of an image can point at a new version after a commit, so it
+	// is only cached for a while and revalidated with its etag
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	w.Header().Set("ETag", img.ETag)
+
+	if r.Header.Get("If-None-Match") == img.ETag {
+		w.WriteHeader(http.StatusNotModified)
+		return nil
+	}
+
+	w.Header().Set("Content-Type", img.ContentType)
+	w.Write(img.Data)
+
+	return nil
+}
+
+// History is the article history handler, it lists the commits that changed
+// the article
+func (b *Blog) History(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	log := GetLog(ctx)
+
+	log.Info("History handler called")
+
+	tid, id, err := b.getID(ctx, b.Repo)
+	if err != nil {
+		return ErrorReponse(500, "Could not get commit id", err)
+	}
+
+	article, rest, ok := b.resolveArticle(ctx, r, tid, id)
+	if !ok || !(b.Preview(ctx) || article.Published(time.Now())) {
+		return errors.NewErrorStatus(404, "Article not found")
+	}
+
+	_, page := splitPage(rest)
+	if page < 0 {
+		return errors.NewErrorStatus(404, "Page not found")
+	}
+
+	revisions, count, err := b.revisions(ctx, r, tid, id, article, page)
+	if err != nil {
+		return ErrorReponse(500, "Could not get article history", err)
+	}
+
+	log.Info("Loaded article history")
+
+	model := &HistoryModel{
+		ArticleModel: b.ArticleModel(ctx, r, article),
+		Page:         page,
+		Count:        count,
+		Revisions:    revisions,
+	}
+
+	var buffer bytes.Buffer
+	err = b.Cache.GetTemplate(tid, id, "history.tpl").Execute(&buffer, model)
+	if err != nil {
+		return ErrorReponse(500, "Could not execute history template", err)
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	w.Write(buffer.Bytes())
+
+	return nil
+}
+
+// Diff is the article diff handler, it shows the changes to the article
+// source between two commits. The split view is shown with ?view=split
+func (b *Blog) Diff(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	log := GetLog(ctx)
+
+	log.Info("Diff handler called")
+
+	tid, id, err := b.getID(ctx, b.Repo)
+	if err != nil {
+		return ErrorReponse(500, "Could not get commit id", err)
+	}
+
+	article, rest, ok := b.resolveArticle(ctx, r, tid, id)
+	if !ok || !(b.Preview(ctx) || article.Published(time.Now())) {
+		return errors.NewErrorStatus(404, "Article not found")
+	}
+
+	parts := strings.Split(rest, "/")
+	if len(parts) != 3 || parts[0] != "diff" {
+		return errors.NewErrorStatus(404, "Diff not found")
+	}
+
+	from, fromSource, err := b.source(r, article, parts[1])
+	if err != nil {
+		return errors.ConvertErrorStatus(404, err)
+	}
+
+	to, toSource, err := b.source(r, article, parts[2])
+	if err != nil {
+		return errors.ConvertErrorStatus(404, err)
+	}
+
+	fromLines, toLines := DiffLines(fromSource), DiffLines(toSource)
+	if len(fromLines)+len(toLines) > MaxDiffLines {
+		return errors.NewErrorStatus(422, "Diff is too large to show")
+	}
+
+	lines := Diff(fromLines, toLines)
+
+	log.Info("Generated article diff")
+
+	model := &DiffModel{
+		ArticleModel: b.ArticleModel(ctx, r, article),
+		From:         from,
+		To:           to,
+		Split:        r.URL.Query().Get("view") == "split",
+		Lines:        lines,
+		Rows:         SideBySide(lines),
+	}
+
+	var buffer bytes.Buffer
+	err = b.Cache.GetTemplate(tid, id, "diff.tpl").Execute(&buffer, model)
+	if err != nil {
+		return ErrorReponse(500, "Could not execute diff template", err)
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	w.Write(buffer.Bytes())
+
+	return nil
+}
+
+// source gets the source of an article at a commit, an article that does not
+// exist at the commit has an empty source
+func (b *Blog) source(r *http.Request, article *Article, commitID string) (*Revision, string, error) {
+	commit, err := b.Repo.GetCommit(commitID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	revision := NewRevision(commit, r.Host, article)
+
+	data, err := readBlob(&commit.Tree, article.Path)
+	if err == git.ErrNotExist {
+		return revision, "", nil
+	} else if err != nil {
+		return nil, "", err
+	}
+
+	return revision, string(data), nil
+}
+
+// NestedArticle routes requests for articles in sections, the article name
+// spans several path segments so the route can not be matched by the router
+func (b *Blog) NestedArticle(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	tid, id, err := b.getID(ctx, b.Repo)
+	if err != nil {
+		return ErrorReponse(500, "Could not get commit id", err)
+	}
+
+	_, rest, ok := b.resolveArticle(ctx, r, tid, id)
+	if !ok {
+		return errors.NewErrorStatus(404, "Article not found")
+	}
+
+	switch strings.SplitN(rest, "/", 2)[0] {
+	case "":
+		return b.Article(ctx, w, r)
+	case "history":
+		return b.History(ctx, w, r)
+	case "diff":
+		return b.Diff(ctx, w, r)
+	}
+
+	return errors.NewErrorStatus(404, "Article page not found")
+}
+
+// revisions gets a page of commits that changed an article. Branches use the
+// repository history, commits have no branch so the history found when the
+// commit was built is used
+func (b *Blog) revisions(ctx context.Context, r *http.Request, tid string, id string, article *Article, page int) ([]*Revision, int, error) {
+	var commits []*git.Commit
+	var total int
+
+	if branch, ok := b.branch(ctx); ok {
+		list, err := b.Repo.CommitsByFileAndRange(branch, article.Path, page+1)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		for e := list.Front(); e != nil; e = e.Next() {
+			commits = append(commits, e.Value.(*git.Commit))
+		}
+
+		total, err = b.Repo.FileCommitsCount(branch, article.Path)
+		if err != nil {
+			return nil, 0, err
+		}
+	} else {
+		history, ok := b.Cache.GetHistory(tid, id, article.Path)
+		if !ok {
+			return nil, 0, fmt.Errorf("No history for %s", article.Path)
+		}
+
+		total = len(history)
+
+		start, end := page*git.ItemsPerPage, (page+1)*git.ItemsPerPage
+		if start > total {
+			start = total
+		}
+		if end > total {
+			end = total
+		}
+
+		commits = history[start:end]
+	}
+
+	revisions := make([]*Revision, len(commits))
+	for i, commit := range commits {
+		revisions[i] = NewRevision(commit, r.Host, article)
+	}
+
+	count := (total + git.ItemsPerPage - 1) / git.ItemsPerPage
+
+	return revisions, count, nil
+}
+
+// Section is the section handler
+func (b *Blog) Section(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	log := GetLog(ctx)
+
+	log.Info("Section handler called")
+
+	tid, id, err := b.getID(ctx, b.Repo)
+	if err != nil {
+		return ErrorReponse(500, "Could not get commit id", err)
+	}
+
+	name, page := splitPage(b.pathParam(ctx, r, "section"))
+	if page < 0 {
+		return errors.NewErrorStatus(404, "Page not found")
+	}
+
+	index, ok := b.Cache.GetSection(tid, id, name)
+	if !ok {
+		return errors.NewErrorStatus(404, "Section not found")
+	}
+
+	if !b.Preview(ctx) {
+		index = index.Published(time.Now())
+	}
+
+	log.Info("Loaded section from cache")
+
+	model := b.SectionModel(ctx, r, name, index, page)
+
+	var buffer bytes.Buffer
+	err = b.Cache.GetTemplate(tid, id, "section.tpl").Execute(&buffer, model)
+	if err != nil {
+		return ErrorReponse(500, "Could not execute section template", err)
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	w.Write(buffer.Bytes())
+
+	return nil
+}
+
+// Author is the author handler
+func (b *Blog) Author(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	log := GetLog(ctx)
+
+	log.Info("Author handler called")
+
+	if page, _ := scaffold.GetParam(ctx, "page").Int(); page < 0 {
+		return errors.NewErrorStatus(404, "Page not found")
+	}
+
+	tid, id, err := b.getID(ctx, b.Repo)
+	if err != nil {
+		return ErrorReponse(500, "Could not get commit id", err)
+	}
+
+	name, _ := scaffold.GetParam(ctx, "author").String()
+
+	author, ok := b.Cache.GetAuthor(tid, id, name)
+	if !ok {
+		return errors.NewErrorStatus(404, "Author not found")
+	}
+
+	if !b.Preview(ctx) {
+		author = author.Published(time.Now())
+	}
+
+	log.Info("Loaded author from cache")
+
+	model := b.AuthorModel(ctx, r, author)
+
+	var buffer bytes.Buffer
+	err = b.Cache.GetTemplate(tid, id, "author.tpl").Execute(&buffer, model)
+	if err != nil {
+		return ErrorReponse(500, "Could not execute author template", err)
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	w.Write(buffer.Bytes())
+
+	return nil
+}
+
+// Tags is the tag listing handler
+func (b *Blog) Tags(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	return b.taxonomy(ctx, w, r, "tags", "tags.tpl")
+}
+
+// Tag is the handler listing articles with a tag
+func (b *Blog) Tag(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	return b.term(ctx, w, r, "tags", "tag.tpl")
+}
+
+// Categories is the category listing handler
+func (b *Blog) Categories(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	return b.taxonomy(ctx, w, r, "categories", "categories.tpl")
+}
+
+// Category is the handler listing articles in a category
+func (b *Blog) Category(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	return b.term(ctx, w, r, "categories", "category.tpl")
+}
+
+func (b *Blog) taxonomy(ctx context.Context, w http.ResponseWriter, r *http.Request, name string, tpl string) error {
+	log := GetLog(ctx)
+
+	log.WithField("taxonomy", name).Info("Taxonomy handler called")
+
+	tid, id, err := b.getID(ctx, b.Repo)
+	if err != nil {
+		return ErrorReponse(500, "Could not get commit id", err)
+	}
+
+	taxonomy, ok := b.Cache.GetTaxonomy(tid, id, name)
+	if !ok {
+		return errors.NewErrorStatus(404, "Taxonomy not found")
+	}
+
+	if !b.Preview(ctx) {
+		taxonomy = taxonomy.Published(time.Now())
+	}
+
+	log.Info("Loaded taxonomy from cache")
+
+	model := b.TagsModel(ctx, r, name, taxonomy)
+
+	var buffer bytes.Buffer
+	err = b.Cache.GetTemplate(tid, id, tpl).Execute(&buffer, model)
+	if err != nil {
+		return ErrorReponse(500, "Could not execute taxonomy template", err)
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	w.Write(buffer.Bytes())
+
+	return nil
+}
+
+func (b *Blog) term(ctx context.Context, w http.ResponseWriter, r *http.Request, name string, tpl string) error {
+	log := GetLog(ctx)
+
+	log.WithField("taxonomy", name).Info("Term handler called")
+
+	if page, _ := scaffold.GetParam(ctx, "page").Int(); page < 0 {
+		return errors.NewErrorStatus(404, "Page not found")
+	}
+
+	tid, id, err := b.getID(ctx, b.Repo)
+	if err != nil {
+		return ErrorReponse(500, "Could not get commit id", err)
+	}
+
+	taxonomy, ok := b.Cache.GetTaxonomy(tid, id, name)
+	if !ok {
+		return errors.NewErrorStatus(404, "Taxonomy not found")
+	}
+
+	if !b.Preview(ctx) {
+		taxonomy = taxonomy.Published(time.Now())
+	}
+
+	raw, _ := scaffold.GetParam(ctx, "tag").String()
+	slug := Slugify(raw)
+
+	term, ok := taxonomy[slug]
+	if !ok {
+		return errors.NewErrorStatus(404, "Term not found")
+	}
+
+	// Terms are keyed by slug, other spellings of a term redirect to the slug
+	// so each term has a single url
+	if raw != slug {
+		segment := "/" + name + "/" + raw + "/"
+		if i := strings.LastIndex(r.URL.Path, segment); i >= 0 {
+			u := url.URL{
+				Path:     r.URL.Path[:i] + "/" + name + "/" + slug + "/" + r.URL.Path[i+len(segment):],
+				RawQuery: r.URL.RawQuery,
+			}
+
+			log.WithField("term", slug).Info("Redirecting to term slug")
+			http.Redirect(w, r, u.String(), 301)
+			return nil
+		}
+	}
+
+	log.Info("Loaded term from cache")
+
+	model := b.TagModel(ctx, r, name, term)
+
+	var buffer bytes.Buffer
+	err = b.Cache.GetTemplate(tid, id, tpl).Execute(&buffer, model)
+	if err != nil {
+		return ErrorReponse(500, "Could not execute term template", err)
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	w.Write(buffer.Bytes())
+
+	return nil
+}
+
+// Preview checks if the request is for a branch or commit preview, previews
+// include drafts and scheduled articles
+func (b *Blog) Preview(ctx context.Context) bool {
+	return scaffold.GetParam(ctx, "branch") != "" || scaffold.GetParam(ctx, "commit") != ""
+}
+
+// brokenLinks gets the broken links of the commit being previewed, broken
+// links are not shown outside of previews
+func (b *Blog) brokenLinks(ctx context.Context) LinkReport {
+	if !b.Preview(ctx) {
+		return nil
+	}
+
+	tid, id, err := b.getID(ctx, b.Repo)
+	if err != nil {
+		return nil
+	}
+
+	report, _ := b.Cache.GetLinkReport(tid, id)
+	return report
+}
+
+// diagnostics gets the build diagnostics of the commit being previewed for
+// the overlay banner, they are only shown when the site enables build_overlay
+func (b *Blog) diagnostics(ctx context.Context) Diagnostics {
+	if !b.Preview(ctx) {
+		return nil
+	}
+
+	tid, id, err := b.getID(ctx, b.Repo)
+	if err != nil {
+		return nil
+	}
+
+	if site, ok := b.Cache.GetSite(tid, id); !ok || !site.BuildOverlay {
+		return nil
+	}
+
+	diagnostics, _ := b.Cache.GetDiagnostics(tid, id)
+	return diagnostics
+}
+
+// site gets the site config of the commit being served, falling back to the
+// defaults
+func (b *Blog) site(ctx context.Context) *Site {
+	tid, id, err := b.getID(ctx, b.Repo)
+	if err != nil {
+		return DefaultSite()
+	}
+
+	site, ok := b.Cache.GetSite(tid, id)
+	if !ok {
+		return DefaultSite()
+	}
+
+	return site
+}
+
 // BaseURL calculates the base url, for example / or /branch/master/
 func (b *Blog) BaseURL(ctx context.Context, r *http.Request) *url.URL {
 	base := "/"
@@ -122,14 +921,31 @@ func (b *Blog) BaseURL(ctx context.Context, r *http.Request) *url.URL {
 		base = fmt.Sprintf("/commit/%s/", commit)
 	}
 
+	if u := b.site(ctx).URL(); u != nil {
+		return &url.URL{
+			Scheme: u.Scheme,
+			Host:   u.Host,
+			Path:   base,
+		}
+	}
+
 	return &url.URL{
 		Host: r.Host,
 		Path: base,
 	}
 }
 
-// GitURL calculates the git url based on the request host
-func (b *Blog) GitURL(r *http.Request) string {
+// GitURL calculates the git url based on the request host, or the base url
+// of the site config when it sets one
+func (b *Blog) GitURL(ctx context.Context, r *http.Request) string {
+	if u := b.site(ctx).URL(); u != nil {
+		return (&url.URL{
+			Scheme: u.Scheme,
+			Host:   u.Host,
+			Path:   "/blog.git",
+		}).String()
+	}
+
 	return (&url.URL{
 		Scheme: "http",
 		Host:   r.Host,
@@ -144,33 +960,53 @@ func (b *Blog) FileLoaderMiddleware(next scaffold.Handler) scaffold.Handler {
 		article, _ := scaffold.GetParam(ctx, "article").String()
 
 		baseURL := b.BaseURL(ctx, r)
-		basePath := baseURL.Path
-		if article != "" {
-			basePath = path.Join(baseURL.Path, "article", article) + "/"
-		}
+		paths := []string{strings.TrimPrefix(r.URL.Path, baseURL.Path)}
 
-		path := strings.TrimPrefix(r.URL.Path, basePath)
 		tid, id, err := b.getID(ctx, b.Repo)
 
-		if err == nil {
-			if reader, ok := b.Cache.GetFile(tid, id, path); ok {
-				log := GetLog(ctx)
-
-				log.
-					WithField("filepath", path).
-					Info("Serving file")
+		// Files under an article are looked up next to the article first, then
+		// from the root of the tree. Each path falls back to the theme, so
+		// files in the tree take precedence over the theme
+		if err == nil && article != "" {
+			lang, _ := scaffold.GetParam(ctx, "lang").String()
+			paths[0] = strings.TrimPrefix(r.URL.Path, path.Join(baseURL.Path, lang, "article", article)+"/")
+			if a, rest, ok := b.resolveArticle(ctx, r, tid, id); ok {
+				paths = []string{path.Join(a.Section, rest), rest}
+			}
+		}
 
-				if ctype := mime.TypeByExtension(filepath.Ext(r.URL.Path)); ctype != "" {
-					w.Header().Set("Content-Type", ctype)
+		if err == nil {
+			for _, path := range paths {
+				if b.serveFile(ctx, w, r, tid, id, path) {
+					return
 				}
-				io.Copy(w, reader)
-			} else {
-				next.CtxServeHTTP(ctx, w, r)
 			}
 		}
+
+		next.CtxServeHTTP(ctx, w, r)
 	})
 }
 
+func (b *Blog) serveFile(ctx context.Context, w http.ResponseWriter, r *http.Request, tid string, id string, path string) bool {
+	reader, ok := b.Cache.GetFile(tid, id, path)
+	if !ok {
+		return false
+	}
+
+	log := GetLog(ctx)
+
+	log.
+		WithField("filepath", path).
+		Info("Serving file")
+
+	if ctype := mime.TypeByExtension(filepath.Ext(r.URL.Path)); ctype != "" {
+		w.Header().Set("Content-Type", ctype)
+	}
+	io.Copy(w, reader)
+
+	return true
+}
+
 // Routes implements scaffold.Platform.Routes
 func (b *Blog) Routes(router *scaffold.Router) {
 	router.AddHandlerBuilder(errors.HandlerBuilder)
@@ -178,9 +1014,100 @@ func (b *Blog) Routes(router *scaffold.Router) {
 	router.Get("", b.Index)
 	router.Get("page/:page", b.Index)
 	router.Get("article/:article", b.Article)
+	router.Get("section/:section", b.Section)
+	router.Get("authors/:author", b.Author)
+	router.Get("authors/:author/page/:page", b.Author)
+	router.Get("tags", b.Tags)
+	router.Get("tags/:tag", b.Tag)
+	router.Get("tags/:tag/page/:page", b.Tag)
+	router.Get("categories", b.Categories)
+	router.Get("categories/:tag", b.Category)
+	router.Get("categories/:tag/page/:page", b.Category)
+	router.Get("_links", b.Links)
+	router.Get("_build", b.BuildReport)
+
+	router.Get("article/:article/history", b.History)
+	router.Get("article/:article/history/page/:page", b.History)
+	router.Get("article/:article/diff/:from/:to", b.Diff)
+
+	// Nested articles and sections span several path segments, so they are
+	// caught by the not found handler and resolved from the full path
+	router.Route("article/:article").NotFound(b.NestedArticle)
+	router.Route("section/:section").NotFound(b.Section)
+	router.Route("_img/:width").NotFound(b.Image)
+
+	// Translations are served under their language, the index of a language
+	// is served by Page as it shares the :page route
+	router.Get(":lang/page/:page", b.Index)
+	router.Get(":lang/article/:article", b.Article)
+	router.Get(":lang/article/:article/history", b.History)
+	router.Get(":lang/article/:article/history/page/:page", b.History)
+	router.Get(":lang/article/:article/diff/:from/:to", b.Diff)
+	router.Route(":lang/article/:article").NotFound(b.NestedArticle)
 
-	router.Get(":file").Use(b.FileLoaderMiddleware)
+	// Files in the tree take precedence over pages of the same name
+	router.Get(":page", b.Page).Use(b.FileLoaderMiddleware)
 	router.Get("article/:article").Use(b.FileLoaderMiddleware)
+	router.Get(":lang/article/:article").Use(b.FileLoaderMiddleware)
+}
+
+// pathParam gets a parameter that may span several path segments, such as the
+// nested article go/generics, everything after the prefix is returned. The
+// prefix follows the language of translated routes, such as fr/article
+func (b *Blog) pathParam(ctx context.Context, r *http.Request, prefix string) string {
+	lang, _ := scaffold.GetParam(ctx, "lang").String()
+	base := path.Join(b.BaseURL(ctx, r).Path, lang, prefix) + "/"
+	return strings.Trim(strings.TrimPrefix(r.URL.Path, base), "/")
+}
+
+// language gets the language of a translated route, it is empty for the
+// default language. ok is false when the prefix is not one of the languages
+// of the site
+func (b *Blog) language(ctx context.Context) (string, bool) {
+	lang, _ := scaffold.GetParam(ctx, "lang").String()
+	if lang == "" {
+		return "", true
+	}
+
+	return lang, b.site(ctx).HasLanguage(lang)
+}
+
+// resolveArticle finds the article of the request in the language of the
+// route, see Cache.ResolveArticle
+func (b *Blog) resolveArticle(ctx context.Context, r *http.Request, tid string, id string) (*Article, string, bool) {
+	lang, ok := b.language(ctx)
+	if !ok {
+		return nil, "", false
+	}
+
+	return b.Cache.ResolveArticle(tid, id, lang, b.pathParam(ctx, r, "article"))
+}
+
+// splitPage splits a trailing page/:page from a path
+func splitPage(p string) (string, int) {
+	i := strings.LastIndex(p, "page/")
+	if i < 0 || (i > 0 && p[i-1] != '/') {
+		return p, 0
+	}
+
+	page, err := strconv.Atoi(p[i+len("page/"):])
+	if err != nil {
+		return p, 0
+	}
+
+	return strings.TrimSuffix(p[:i], "/"), page
+}
+
+// branch gets the branch being served, commit previews have no branch
+func (b *Blog) branch(ctx context.Context) (string, bool) {
+	if branch, err := scaffold.GetParam(ctx, "branch").String(); branch != "" && err == nil {
+		return branch, true
+	}
+	if commit := scaffold.GetParam(ctx, "commit"); commit != "" {
+		return "", false
+	}
+
+	return "master", true
 }
 
 // Get tree and commit id based off current request
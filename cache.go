@@ -1,16 +1,18 @@
 package blog
 
 import (
+	"fmt"
 	"html/template"
 	"io"
 	"io/ioutil"
+	"path"
 	"sort"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/Sirupsen/logrus"
 	"github.com/gogits/git"
-	"github.com/russross/blackfriday"
 )
 
 type commitInfo struct {
@@ -21,22 +23,47 @@ type commitInfo struct {
 
 type node struct {
 	Created time.Time
+	Site    *Site
 
-	IndexTemplate   *template.Template
-	ArticleTemplate *template.Template
+	Templates map[string]*template.Template
 
 	Index    Index
 	Articles map[string]*Article
+	Pages    map[string]*Article
+	Nav      Pages
+	Sections map[string]Index
 	Tree     *git.Tree
+	Theme    *theme
+
+	Histories Histories
+
+	Languages    map[string]Index
+	Translations map[string]map[string]*Article
+
+	Taxonomies  map[string]Taxonomy
+	Authors     map[string]*AuthorPage
+	Links       LinkReport
+	Diagnostics Diagnostics
+}
+
+// files gets the files of the commit, the tree and then its theme
+func (n node) files() fileTree {
+	if n.Theme == nil {
+		return n.Tree
+	}
+	return layers{n.Tree, n.Theme.Tree}
 }
 
 // Cache gets and caches file trees and articles
 type Cache struct {
-	Repo *git.Repository `inject:""`
+	Repo      *git.Repository `inject:""`
+	Renderers *Renderers      `inject:""`
 
-	lock  sync.RWMutex
-	once  sync.Once
-	cache map[string]node
+	lock    sync.RWMutex
+	once    sync.Once
+	cache   map[string]node
+	images  imageCache
+	changes map[string][]string
 
 	Branches map[string]*commitInfo
 	Commits  map[string]*commitInfo
@@ -184,7 +211,8 @@ func (c *Cache) getIndex(id string) (Index, bool) {
 	return nil, false
 }
 
-// GetFile gets a file from tree and commit ids
+// GetFile gets a file from tree and commit ids, files missing from the tree
+// are read from the theme of the site
 func (c *Cache) GetFile(tid string, id string, path string) (io.Reader, bool) {
 	if c.exists(id) {
 		return c.getFile(id, path)
@@ -198,6 +226,67 @@ func (c *Cache) GetFile(tid string, id string, path string) (io.Reader, bool) {
 }
 
 func (c *Cache) getFile(id string, path string) (io.Reader, bool) {
+	blob, ok := c.getBlob(id, path)
+	if !ok {
+		return nil, false
+	}
+
+	r, err := blob.Data()
+	if err != nil {
+		return nil, false
+	}
+
+	return r, true
+}
+
+// GetImage gets an image from the tree resized to width, resized images are
+// cached by blob sha and width
+func (c *Cache) GetImage(tid string, id string, path string, width int) (*ResizedImage, bool) {
+	if !IsImage(path) || !validImageWidth(width) {
+		return nil, false
+	}
+
+	if !c.exists(id) && !c.Build(tid, id) {
+		return nil, false
+	}
+
+	blob, ok := c.getBlob(id, path)
+	if !ok {
+		return nil, false
+	}
+
+	key := fmt.Sprintf("%s:%d", blob.Id.String(), width)
+	if img, ok := c.images.get(key); ok {
+		return img, true
+	}
+
+	reader, err := blob.Data()
+	if err != nil {
+		return nil, false
+	}
+
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, false
+	}
+
+	img, err := ResizeImage(data, width)
+	if err != nil {
+		logrus.
+			WithError(err).
+			WithField("filename", path).
+			Warn("Image could not be resized")
+
+		return nil, false
+	}
+
+	img.ETag = fmt.Sprintf(`"%s"`, key)
+	c.images.set(key, img)
+
+	return img, true
+}
+
+func (c *Cache) getBlob(id string, path string) (*git.Blob, bool) {
 	c.lock.RLock()
 	defer c.lock.RUnlock()
 
@@ -206,17 +295,12 @@ func (c *Cache) getFile(id string, path string) (io.Reader, bool) {
 	}
 
 	if n, ok := c.cache[id]; ok {
-		blob, err := n.Tree.GetBlobByPath(path)
-		if err != nil {
-			return nil, false
-		}
-
-		r, err := blob.Data()
+		blob, err := n.files().GetBlobByPath(path)
 		if err != nil {
 			return nil, false
 		}
 
-		return r, true
+		return blob, true
 	}
 
 	return nil, false
@@ -224,20 +308,31 @@ func (c *Cache) getFile(id string, path string) (io.Reader, bool) {
 
 // GetIndexTemplate gets the template from the cache
 func (c *Cache) GetIndexTemplate(tid string, id string) *template.Template {
+	return c.GetTemplate(tid, id, "index.tpl")
+}
+
+// GetArticleTemplate gets the template from the cache
+func (c *Cache) GetArticleTemplate(tid string, id string) *template.Template {
+	return c.GetTemplate(tid, id, "article.tpl")
+}
+
+// GetTemplate gets a template by file name from the cache, falling back to
+// the default template
+func (c *Cache) GetTemplate(tid string, id string, name string) *template.Template {
 	if c.exists(id) {
-		if tpl, ok := c.getIndexTemplate(id); ok {
+		if tpl, ok := c.getTemplate(id, name); ok {
 			return tpl
 		}
 	} else if c.Build(tid, id) {
-		if tpl, ok := c.getIndexTemplate(id); ok {
+		if tpl, ok := c.getTemplate(id, name); ok {
 			return tpl
 		}
 	}
 
-	return IndexTemplate
+	return defaultTemplates[name]
 }
 
-func (c *Cache) getIndexTemplate(id string) (*template.Template, bool) {
+func (c *Cache) getTemplate(id string, name string) (*template.Template, bool) {
 	c.lock.RLock()
 	defer c.lock.RUnlock()
 
@@ -246,96 +341,206 @@ func (c *Cache) getIndexTemplate(id string) (*template.Template, bool) {
 	}
 
 	if n, ok := c.cache[id]; ok {
-		return n.IndexTemplate, true
+		tpl, ok := n.Templates[name]
+		return tpl, ok
 	}
 
 	return nil, false
 }
 
-// GetArticleTemplate gets the template from the cache
-func (c *Cache) GetArticleTemplate(tid string, id string) *template.Template {
-	if c.exists(id) {
-		if tpl, ok := c.getArticleTemplate(id); ok {
-			return tpl
-		}
-	} else if c.Build(tid, id) {
-		if tpl, ok := c.getArticleTemplate(id); ok {
-			return tpl
+// TemplateDir is the directory of the tree templates are loaded from
+const TemplateDir = "templates"
+
+// templateSource is the source of a single template file, Path is the file in
+// the tree or empty for the defaults
+type templateSource struct {
+	Name   string
+	Path   string
+	Source string
+}
+
+// buildTemplates builds a template set for every page. The defaults are
+// overridden by file name with the templates directory of the theme and then
+// of the tree, pages in the root of the tree are still honoured when
+// templates/ does not have them
+func (c *Cache) buildTemplates(tree *git.Tree, theme *theme, site *Site, diagnostics *Diagnostics, index Index, nav Pages) map[string]*template.Template {
+	var themeFiles []templateSource
+	if theme != nil {
+		themeFiles = c.readTemplates(theme.Tree, theme.Path, diagnostics)
+	}
+
+	var rootFiles []templateSource
+	for _, name := range PageTemplates {
+		if data, err := readBlob(tree, name); err == nil {
+			rootFiles = append(rootFiles, templateSource{Name: name, Path: name, Source: string(data)})
 		}
 	}
 
-	return ArticleTemplate
+	treeFiles := c.readTemplates(tree, "", diagnostics)
+
+	return compileTemplates(themeFiles, rootFiles, treeFiles, templateFuncs(site, index, nav), diagnostics)
 }
 
-func (c *Cache) getArticleTemplate(id string) (*template.Template, bool) {
-	c.lock.RLock()
-	defer c.lock.RUnlock()
+// compileTemplates parses the templates read by buildTemplates, each layer
+// overrides the ones before it by file name. Shared templates that do not
+// parse fall back to defaultTemplates, as does a page that does not parse
+func compileTemplates(themeFiles, rootFiles, treeFiles []templateSource, funcs template.FuncMap, diagnostics *Diagnostics) map[string]*template.Template {
+	overrides := make(map[string]templateSource)
+	for _, files := range [][]templateSource{themeFiles, rootFiles, treeFiles} {
+		for _, source := range files {
+			overrides[source.Name] = source
+		}
+	}
 
-	if c.cache == nil {
-		return nil, false
+	// Theme files the tree replaces are dropped, the rest go before the
+	// files of the tree so the partials the tree defines win
+	var files []templateSource
+	for _, source := range themeFiles {
+		if overrides[source.Name].Path == source.Path {
+			files = append(files, source)
+		}
+	}
+	files = append(files, treeFiles...)
+
+	// Defaults go first so partials defined in other files of the tree
+	// replace them
+	var shared []templateSource
+	for _, name := range sortedTemplateNames(DefaultTemplates) {
+		if _, ok := overrides[name]; !ok && !isPageTemplate(name) {
+			shared = append(shared, templateSource{Name: name, Source: DefaultTemplates[name]})
+		}
+	}
+	for _, file := range files {
+		if !isPageTemplate(file.Name) {
+			shared = append(shared, file)
+		}
 	}
 
-	if n, ok := c.cache[id]; ok {
-		return n.ArticleTemplate, true
+	set, err := parseTemplateSet(shared, funcs)
+	if err != nil {
+		logrus.WithError(err).Error("Could not parse templates")
+		diagnostics.Add(SeverityError, TemplateDir, "Templates could not be parsed", err)
+		return defaultTemplates
 	}
 
-	return nil, false
-}
+	templates := make(map[string]*template.Template)
+	for _, name := range PageTemplates {
+		source, ok := overrides[name]
+		if !ok {
+			source = templateSource{Name: name, Source: DefaultTemplates[name]}
+		}
 
-func (c *Cache) buildIndexTemplate(tree *git.Tree) *template.Template {
-	blob, err := tree.GetBlobByPath("index.tpl")
-	if err != nil {
-		logrus.WithError(err).Error("Could not read template blob")
-		return IndexTemplate
+		tpl, err := parsePageTemplate(set, source)
+		if err != nil {
+			logrus.WithError(err).WithField("template", name).Error("Could not parse template")
+			diagnostics.Add(SeverityError, source.Path, "Template could not be parsed", err)
+			tpl = defaultTemplates[name]
+		}
+
+		templates[name] = tpl
 	}
 
-	reader, err := blob.Data()
-	if err != nil {
-		logrus.WithError(err).Error("Could not read template blob data")
-		return IndexTemplate
+	return templates
+}
+
+// readTemplates reads the templates directory of a tree, prefix locates the
+// tree in diagnostics
+func (c *Cache) readTemplates(tree *git.Tree, prefix string, diagnostics *Diagnostics) []templateSource {
+	entry, err := tree.GetTreeEntryByPath(TemplateDir)
+	if err != nil || !entry.IsDir() {
+		return nil
 	}
 
-	bytes, err := ioutil.ReadAll(reader)
+	var files []templateSource
+	err = c.walk(git.NewTree(c.Repo, entry.Id), "", func(name string, entry *git.TreeEntry) {
+		if path.Ext(name) != ".tpl" {
+			return
+		}
+
+		file := path.Join(TemplateDir, name)
+		data, err := readBlob(tree, file)
+		if err != nil {
+			logrus.WithError(err).WithField("template", prefix+name).Error("Could not read template")
+			diagnostics.Add(SeverityError, prefix+file, "Template could not be read", err)
+			return
+		}
+
+		files = append(files, templateSource{Name: name, Path: prefix + file, Source: string(data)})
+	})
 	if err != nil {
-		logrus.WithError(err).Error("Could not read template data")
-		return IndexTemplate
+		logrus.WithError(err).Warn("Template directory could not be read")
+		diagnostics.Add(SeverityError, prefix+TemplateDir, "Template directory could not be read", err)
 	}
 
-	tpl, err := template.New("index").Parse(string(bytes))
-	if err != nil {
-		logrus.WithError(err).Error("Could parse template")
-		return IndexTemplate
+	return files
+}
+
+// parseTemplateSet parses the layouts and partials shared by every page, a
+// file that does not parse is returned as a *Diagnostic
+func parseTemplateSet(shared []templateSource, funcs template.FuncMap) (*template.Template, error) {
+	set := template.New("").Funcs(funcs)
+
+	for _, file := range shared {
+		if _, err := set.New(file.Name).Parse(file.Source); err != nil {
+			return nil, NewDiagnostic(SeverityError, file.Path, "Template could not be parsed, using the default templates", err)
+		}
 	}
 
-	return tpl
+	return set, nil
 }
 
-func (c *Cache) buildArticleTemplate(tree *git.Tree) *template.Template {
-	blob, err := tree.GetBlobByPath("article.tpl")
+// parsePageTemplate adds a page to a copy of the shared set, the page is
+// parsed last so the blocks it defines override the layout
+func parsePageTemplate(set *template.Template, page templateSource) (*template.Template, error) {
+	clone, err := set.Clone()
 	if err != nil {
-		logrus.WithError(err).Error("Could not read template blob")
-		return ArticleTemplate
+		return nil, err
 	}
 
-	reader, err := blob.Data()
+	tpl, err := clone.New(page.Name).Parse(page.Source)
 	if err != nil {
-		logrus.WithError(err).Error("Could not read template blob data")
-		return ArticleTemplate
+		return nil, NewDiagnostic(SeverityError, page.Path, "Template could not be parsed, using the default "+page.Name, err)
 	}
 
-	bytes, err := ioutil.ReadAll(reader)
-	if err != nil {
-		logrus.WithError(err).Error("Could not read template data")
-		return ArticleTemplate
+	return tpl, nil
+}
+
+// defaultTemplates are the default template sets, used when the templates of
+// a tree do not parse
+var defaultTemplates = func() map[string]*template.Template {
+	var shared []templateSource
+	for _, name := range sortedTemplateNames(DefaultTemplates) {
+		if !isPageTemplate(name) {
+			shared = append(shared, templateSource{Name: name, Source: DefaultTemplates[name]})
+		}
 	}
 
-	tpl, err := template.New("index").Parse(string(bytes))
-	if err != nil {
-		logrus.WithError(err).Error("Could parse template")
-		return ArticleTemplate
+	set := template.Must(parseTemplateSet(shared, templateFuncs(DefaultSite(), nil, nil)))
+
+	templates := make(map[string]*template.Template)
+	for _, name := range PageTemplates {
+		templates[name] = template.Must(parsePageTemplate(set, templateSource{Name: name, Source: DefaultTemplates[name]}))
+	}
+
+	return templates
+}()
+
+func isPageTemplate(name string) bool {
+	for _, page := range PageTemplates {
+		if page == name {
+			return true
+		}
 	}
+	return false
+}
 
-	return tpl
+func sortedTemplateNames(templates map[string]string) []string {
+	names := make([]string, 0, len(templates))
+	for name := range templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
 }
 
 // GetArticle gets an article from tree and commit ids
@@ -368,151 +573,906 @@ func (c *Cache) getArticle(id string, article string) (*Article, bool) {
 	return nil, false
 }
 
-func (c *Cache) exists(id string) bool {
+// GetPage gets a standalone page from tree and commit ids
+func (c *Cache) GetPage(tid string, id string, page string) (*Article, bool) {
+	if c.exists(id) {
+		return c.getPage(id, page)
+	}
+
+	if c.Build(tid, id) {
+		return c.getPage(id, page)
+	}
+
+	return nil, false
+}
+
+func (c *Cache) getPage(id string, page string) (*Article, bool) {
 	c.lock.RLock()
 	defer c.lock.RUnlock()
 
 	if c.cache == nil {
-		return false
+		return nil, false
 	}
 
-	n, ok := c.cache[id]
+	if n, ok := c.cache[id]; ok {
+		if page, ok := n.Pages[page]; ok {
+			return page, true
+		}
+	}
 
-	return ok && time.Since(n.Created) < (time.Minute*5)
+	return nil, false
 }
 
-// Build gets and caches information on a tree and commit id combo
-func (c *Cache) Build(tid string, id string) bool {
-	c.lock.Lock()
-	defer c.lock.Unlock()
+// GetTranslation gets an article translated into a language from tree and
+// commit ids, the default language is not a translation
+func (c *Cache) GetTranslation(tid string, id string, lang string, article string) (*Article, bool) {
+	if c.exists(id) {
+		return c.getTranslation(id, lang, article)
+	}
 
-	c.once.Do(c.startClean)
+	if c.Build(tid, id) {
+		return c.getTranslation(id, lang, article)
+	}
 
-	logrus.WithField("commit", id).WithField("tree", tid).Info("Building cache")
+	return nil, false
+}
+
+func (c *Cache) getTranslation(id string, lang string, article string) (*Article, bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
 
 	if c.cache == nil {
-		c.cache = make(map[string]node)
+		return nil, false
 	}
 
-	sha1, err := git.NewIdFromString(tid)
-	if err != nil {
-		logrus.WithError(err).WithField("tree", tid).Error("Could not build data")
-		return false
+	if n, ok := c.cache[id]; ok {
+		if article, ok := n.Translations[lang][article]; ok {
+			return article, true
+		}
 	}
 
-	tree := git.NewTree(c.Repo, sha1)
+	return nil, false
+}
 
-	n := node{
-		Articles: make(map[string]*Article),
-		Tree:     tree,
-		Created:  time.Now(),
+// GetLanguageIndex gets the index of the articles translated into a language
+// from tree and commit ids, the default language gets the main index
+func (c *Cache) GetLanguageIndex(tid string, id string, lang string) (Index, bool) {
+	if c.exists(id) {
+		return c.getLanguageIndex(id, lang)
 	}
 
-	scanner, err := tree.Scanner()
-	if err != nil {
-		logrus.WithError(err).WithField("tree", tid).Error("Could not build data")
-		return false
+	if c.Build(tid, id) {
+		return c.getLanguageIndex(id, lang)
 	}
 
-	for scanner.Scan() {
-		entry := scanner.TreeEntry()
+	return nil, false
+}
 
-		name := entry.Name()
+func (c *Cache) getLanguageIndex(id string, lang string) (Index, bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
 
-		if entry.IsDir() {
-			logrus.
-				WithField("tree", tid).
-				WithField("directory", name).
-				Info("Directory ignored")
+	if c.cache == nil {
+		return nil, false
+	}
 
-			continue
+	if n, ok := c.cache[id]; ok {
+		if lang == "" {
+			return n.Index, true
 		}
+		return n.Languages[lang], true
+	}
 
-		if len(name) <= 3 || name[len(name)-3:] != ".md" {
-			logrus.
-				WithField("tree", tid).
-				WithField("filename", name).
-				Info("Non markdown file ignored")
-
-			continue
-		}
+	return nil, false
+}
 
-		reader, err := entry.Blob().Data()
-		if err != nil {
-			logrus.
-				WithError(err).
-				WithField("tree", tid).
-				WithField("filename", name).
-				Warn("File blob could not be generated")
+// ResolveArticle finds the article a path belongs to, the path may continue
+// past the article name, for example go/generics/image.png, the remainder of
+// the path is returned alongside the article. Translations are found when
+// lang is set
+func (c *Cache) ResolveArticle(tid string, id string, lang string, p string) (*Article, string, bool) {
+	p = strings.Trim(p, "/")
+	name, rest := p, ""
 
-			continue
+	get := func(name string) (*Article, bool) {
+		return c.GetArticle(tid, id, name)
+	}
+	if lang != "" {
+		get = func(name string) (*Article, bool) {
+			return c.GetTranslation(tid, id, lang, name)
 		}
+	}
 
-		markdown, err := ioutil.ReadAll(reader)
-		if err != nil {
-			logrus.
-				WithError(err).
-				WithField("tree", tid).
-				WithField("filename", name).
-				Warn("File blob could not be read")
+	for name != "" {
+		if article, ok := get(name); ok {
+			return article, rest, true
+		}
 
-			continue
+		i := strings.LastIndex(name, "/")
+		if i < 0 {
+			break
 		}
 
-		commit, err := c.Repo.GetCommit(id)
-		if err != nil {
-			logrus.
-				WithError(err).
-				WithField("commit", id).
-				Warn("Could not get commit")
+		name, rest = name[:i], strings.TrimPrefix(p[i:], "/")
+	}
 
-			continue
-		}
+	return nil, p, false
+}
 
-		fileCommit, err := commit.GetCommitOfRelPath(name)
-		if err != nil {
-			logrus.
-				WithError(err).
-				WithField("commit", id).
-				WithField("filename", name).
-				Warn("Could not get relative commit")
+// GetSection gets the articles in a section from tree and commit ids
+func (c *Cache) GetSection(tid string, id string, section string) (Index, bool) {
+	if c.exists(id) {
+		return c.getSection(id, section)
+	}
 
-			continue
-		}
+	if c.Build(tid, id) {
+		return c.getSection(id, section)
+	}
 
-		if fileCommit.Committer == nil {
-			logrus.
-				WithError(err).
-				WithField("commit", fileCommit.Id.String()).
-				Warn("Committer information not set")
+	return nil, false
+}
 
-			continue
-		}
+func (c *Cache) getSection(id string, section string) (Index, bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	if c.cache == nil {
+		return nil, false
+	}
 
-		article := Article{
-			Name: name[:len(name)-3],
-			Mod:  fileCommit.Committer.When,
-			Data: blackfriday.MarkdownCommon(markdown),
+	if n, ok := c.cache[id]; ok {
+		if index, ok := n.Sections[section]; ok {
+			return index, true
 		}
+	}
 
-		logrus.
-			WithField("commit", id).
-			WithField("tree", tid).
-			WithField("article", article.Name).
-			Info("Article cached")
+	return nil, false
+}
 
-		n.Index = append(n.Index, article)
-		n.Articles[article.Name] = &article
+// GetTaxonomy gets a taxonomy, such as tags, from tree and commit ids
+func (c *Cache) GetTaxonomy(tid string, id string, name string) (Taxonomy, bool) {
+	if c.exists(id) {
+		return c.getTaxonomy(id, name)
 	}
 
-	sort.Sort(n.Index)
+	if c.Build(tid, id) {
+		return c.getTaxonomy(id, name)
+	}
 
-	n.IndexTemplate = c.buildIndexTemplate(tree)
-	n.ArticleTemplate = c.buildArticleTemplate(tree)
+	return nil, false
+}
 
-	c.cache[id] = n
+func (c *Cache) getTaxonomy(id string, name string) (Taxonomy, bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
 
-	logrus.WithField("commit", id).WithField("tree", tid).Info("Cache built")
+	if c.cache == nil {
+		return nil, false
+	}
+
+	if n, ok := c.cache[id]; ok {
+		if taxonomy, ok := n.Taxonomies[name]; ok {
+			return taxonomy, true
+		}
+	}
+
+	return nil, false
+}
+
+// GetAuthor gets an author page from tree and commit ids
+func (c *Cache) GetAuthor(tid string, id string, author string) (*AuthorPage, bool) {
+	if c.exists(id) {
+		return c.getAuthor(id, author)
+	}
+
+	if c.Build(tid, id) {
+		return c.getAuthor(id, author)
+	}
+
+	return nil, false
+}
+
+func (c *Cache) getAuthor(id string, author string) (*AuthorPage, bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	if c.cache == nil {
+		return nil, false
+	}
+
+	if n, ok := c.cache[id]; ok {
+		if page, ok := n.Authors[author]; ok {
+			return page, true
+		}
+	}
+
+	return nil, false
+}
+
+// GetSite gets the site config of a commit
+func (c *Cache) GetSite(tid string, id string) (*Site, bool) {
+	if c.exists(id) {
+		return c.getSite(id)
+	}
+
+	if c.Build(tid, id) {
+		return c.getSite(id)
+	}
+
+	return nil, false
+}
+
+func (c *Cache) getSite(id string) (*Site, bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	if c.cache == nil {
+		return nil, false
+	}
+
+	if n, ok := c.cache[id]; ok {
+		return n.Site, true
+	}
+
+	return nil, false
+}
+
+// GetDiagnostics gets the problems found while building a commit
+func (c *Cache) GetDiagnostics(tid string, id string) (Diagnostics, bool) {
+	if c.exists(id) {
+		return c.getDiagnostics(id)
+	}
+
+	if c.Build(tid, id) {
+		return c.getDiagnostics(id)
+	}
+
+	return nil, false
+}
+
+func (c *Cache) getDiagnostics(id string) (Diagnostics, bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	if c.cache == nil {
+		return nil, false
+	}
+
+	if n, ok := c.cache[id]; ok {
+		return n.Diagnostics, true
+	}
+
+	return nil, false
+}
+
+// GetLinkReport gets the broken links found while building the commit
+func (c *Cache) GetLinkReport(tid string, id string) (LinkReport, bool) {
+	if c.exists(id) {
+		return c.getLinkReport(id)
+	}
+
+	if c.Build(tid, id) {
+		return c.getLinkReport(id)
+	}
+
+	return nil, false
+}
+
+func (c *Cache) getLinkReport(id string) (LinkReport, bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	if c.cache == nil {
+		return nil, false
+	}
+
+	if n, ok := c.cache[id]; ok {
+		return n.Links, true
+	}
+
+	return nil, false
+}
+
+// GetHistory gets the commits that changed a file, newest first, from tree and
+// commit ids
+func (c *Cache) GetHistory(tid string, id string, path string) ([]*git.Commit, bool) {
+	if c.exists(id) {
+		return c.getHistory(id, path)
+	}
+
+	if c.Build(tid, id) {
+		return c.getHistory(id, path)
+	}
+
+	return nil, false
+}
+
+func (c *Cache) getHistory(id string, path string) ([]*git.Commit, bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	if c.cache == nil {
+		return nil, false
+	}
+
+	if n, ok := c.cache[id]; ok {
+		if history, ok := n.Histories[path]; ok {
+			return history, true
+		}
+	}
+
+	return nil, false
+}
+
+func (c *Cache) exists(id string) bool {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	if c.cache == nil {
+		return false
+	}
+
+	n, ok := c.cache[id]
+
+	return ok && time.Since(n.Created) < (time.Minute*5) && !c.themeMoved(n.Theme)
+}
+
+// Build gets and caches information on a tree and commit id combo
+func (c *Cache) Build(tid string, id string) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.once.Do(c.startClean)
+
+	logrus.WithField("commit", id).WithField("tree", tid).Info("Building cache")
+
+	if c.cache == nil {
+		c.cache = make(map[string]node)
+	}
+
+	sha1, err := git.NewIdFromString(tid)
+	if err != nil {
+		logrus.WithError(err).WithField("tree", tid).Error("Could not build data")
+		return false
+	}
+
+	tree := git.NewTree(c.Repo, sha1)
+
+	n := node{
+		Articles:     make(map[string]*Article),
+		Pages:        make(map[string]*Article),
+		Languages:    make(map[string]Index),
+		Translations: make(map[string]map[string]*Article),
+		Sections:     make(map[string]Index),
+		Tree:         tree,
+		Created:      time.Now(),
+		Taxonomies: map[string]Taxonomy{
+			"tags":       make(Taxonomy),
+			"categories": make(Taxonomy),
+		},
+	}
+
+	commit, err := c.Repo.GetCommit(id)
+	if err != nil {
+		logrus.WithError(err).WithField("commit", id).Error("Could not get commit")
+		return false
+	}
+
+	n.Site, err = LoadSite(tree)
+	if err != nil {
+		logrus.
+			WithError(err).
+			WithField("tree", tid).
+			Warn("Site config could not be parsed, using defaults")
+
+		n.Diagnostics.Add(SeverityWarning, "", "Site config could not be parsed, using defaults", err)
+	}
+
+	if c.changes == nil {
+		c.changes = make(map[string][]string)
+	}
+
+	// The history is walked once for every file, the files changed by each
+	// commit are kept between builds
+	n.Histories, err = FileHistories(c.Repo, commit, c.changes)
+	if err != nil {
+		logrus.WithError(err).WithField("commit", id).Error("Could not get file history")
+		return false
+	}
+
+	n.Theme, err = c.loadTheme(tree, n.Site)
+	if err != nil {
+		logrus.
+			WithError(err).
+			WithField("tree", tid).
+			Warn("Theme could not be loaded")
+
+		n.Diagnostics.Add(SeverityWarning, ThemesDir, "Theme could not be loaded, using the default templates", err)
+	}
+
+	// Every language has a map of translations, even when empty, so links to
+	// the language are known
+	for _, lang := range n.Site.Languages {
+		if n.Site.HasLanguage(lang) {
+			n.Translations[lang] = make(map[string]*Article)
+		}
+	}
+
+	var articles, translations []*Article
+
+	err = c.walk(tree, "", func(name string, entry *git.TreeEntry) {
+		if strings.HasPrefix(name, "authors/") || strings.HasPrefix(name, PagesDir+"/") || strings.HasPrefix(name, ThemesDir+"/") || strings.HasPrefix(name, TemplateDir+"/") {
+			return
+		}
+
+		article, ok := c.buildArticle(commit, n.Histories, tree, n.Site, &n.Diagnostics, name, entry)
+		if !ok {
+			return
+		}
+
+		// post.md and post.en.md are the same article when en is the default
+		// language, the first file found is kept
+		existing := n.Articles
+		if article.Language != n.Site.Language {
+			existing = n.Translations[article.Language]
+		}
+		if other, ok := existing[article.Name]; ok {
+			logrus.
+				WithField("commit", id).
+				WithField("tree", tid).
+				WithField("filename", name).
+				WithField("other", other.Path).
+				Warn("Article name already used")
+
+			n.Diagnostics.Add(SeverityError, name, "Article has the same name and language as "+other.Path+", the file was dropped", nil)
+			return
+		}
+
+		if lang := article.Language; lang != n.Site.Language {
+			logrus.
+				WithField("commit", id).
+				WithField("tree", tid).
+				WithField("article", article.Name).
+				WithField("language", lang).
+				Info("Translation cached")
+
+			translations = append(translations, article)
+			n.Translations[lang][article.Name] = article
+			return
+		}
+
+		logrus.
+			WithField("commit", id).
+			WithField("tree", tid).
+			WithField("article", article.Name).
+			Info("Article cached")
+
+		articles = append(articles, article)
+		n.Articles[article.Name] = article
+	})
+
+	if err != nil {
+		logrus.WithError(err).WithField("tree", tid).Error("Could not build data")
+		return false
+	}
+
+	n.Nav = c.buildPages(commit, n.Histories, tree, n.Site, &n.Diagnostics)
+	for _, page := range n.Nav {
+		n.Pages[page.Name] = page
+	}
+
+	// Titles are known once front matter is applied, translations have to be
+	// linked before articles are copied into the indexes
+	linkTranslations(n.Site, append(append([]*Article(nil), articles...), translations...))
+
+	// Wiki links and backlinks can only be resolved once every article is
+	// known, this has to happen before articles are copied into the indexes
+	for _, article := range articles {
+		section := article.Section
+		article.Data = expandWikiLinks(article.Data, func(target string) (*Article, bool) {
+			return resolveWikiLink(n.Articles, section, target)
+		})
+		article.Excerpt, article.More = Summarize(article.Data, n.Site.SummaryLength)
+	}
+
+	// Translations link to articles in their own language first, backlinks
+	// are only kept for the default language
+	for _, article := range translations {
+		section, lang := article.Section, article.Language
+		article.Data = expandWikiLinks(article.Data, func(target string) (*Article, bool) {
+			if linked, ok := resolveWikiLink(n.Translations[lang], section, target); ok {
+				return linked, true
+			}
+			return resolveWikiLink(n.Articles, section, target)
+		})
+		article.Excerpt, article.More = Summarize(article.Data, n.Site.SummaryLength)
+
+		n.Languages[lang] = append(n.Languages[lang], *article)
+	}
+
+	for _, index := range n.Languages {
+		sort.Sort(index)
+	}
+
+	for _, page := range n.Nav {
+		page.Data = expandWikiLinks(page.Data, func(target string) (*Article, bool) {
+			return resolveWikiLink(n.Articles, "", target)
+		})
+	}
+
+	for _, article := range articles {
+		for _, linked := range linkedArticles(article.Data, n.Articles) {
+			if linked != article {
+				linked.Backlinks = append(linked.Backlinks, *article)
+			}
+		}
+	}
+
+	for _, article := range articles {
+		sort.Sort(article.Backlinks)
+
+		n.Index = append(n.Index, *article)
+
+		for _, section := range article.Sections() {
+			n.Sections[section] = append(n.Sections[section], *article)
+		}
+
+		n.Taxonomies["tags"].Add(*article, article.Tags)
+		n.Taxonomies["categories"].Add(*article, article.Categories)
+	}
+
+	sort.Sort(n.Index)
+
+	for _, section := range n.Sections {
+		sort.Sort(section)
+	}
+
+	for _, taxonomy := range n.Taxonomies {
+		taxonomy.Sort()
+	}
+
+	n.Authors = c.buildAuthors(tree, n.Site, &n.Diagnostics, n.Index)
+
+	n.Templates = c.buildTemplates(tree, n.Theme, n.Site, &n.Diagnostics, n.Index, n.Nav)
+
+	n.Links = c.buildLinkReport(tree, n)
+
+	sort.Sort(n.Diagnostics)
+
+	for _, link := range n.Links {
+		logrus.
+			WithField("commit", id).
+			WithField("source", link.Source).
+			WithField("link", link.Link).
+			WithField("reason", link.Reason).
+			Warn("Broken link")
+	}
+
+	c.cache[id] = n
+
+	logrus.WithField("commit", id).WithField("tree", tid).Info("Cache built")
 
 	return true
 }
+
+// buildLinkReport checks the links in every article, bio and template of the
+// commit, links to theme files are accepted but the theme itself is not
+// checked
+func (c *Cache) buildLinkReport(tree *git.Tree, n node) LinkReport {
+	var report LinkReport
+	links := newLinkChecker(n)
+
+	for _, article := range n.Articles {
+		report = append(report, links.check(article.Path, article.Data, true)...)
+	}
+
+	for _, translated := range n.Translations {
+		for _, article := range translated {
+			report = append(report, links.check(article.Path, article.Data, true)...)
+		}
+	}
+
+	for _, page := range n.Pages {
+		report = append(report, links.check(page.Path, page.Data, true)...)
+	}
+
+	for id, author := range n.Authors {
+		if len(author.Bio) > 0 {
+			report = append(report, links.check("authors/"+id+".md", author.Bio, true)...)
+		}
+	}
+
+	for _, name := range PageTemplates {
+		if data, err := readBlob(tree, name); err == nil {
+			report = append(report, links.check(name, data, false)...)
+		}
+	}
+
+	if entry, err := tree.GetTreeEntryByPath(TemplateDir); err == nil && entry.IsDir() {
+		c.walk(git.NewTree(c.Repo, entry.Id), TemplateDir, func(name string, entry *git.TreeEntry) {
+			if data, err := readBlob(tree, name); err == nil && path.Ext(name) == ".tpl" {
+				report = append(report, links.check(name, data, false)...)
+			}
+		})
+	}
+
+	sort.Sort(report)
+
+	return report
+}
+
+// buildPages builds the pages in the pages directory, pages are served from a
+// single path segment so files in subdirectories are left as plain files
+func (c *Cache) buildPages(commit *git.Commit, histories Histories, tree *git.Tree, site *Site, diagnostics *Diagnostics) Pages {
+	entry, err := tree.GetTreeEntryByPath(PagesDir)
+	if err != nil || !entry.IsDir() {
+		return nil
+	}
+
+	var pages Pages
+
+	err = c.walk(git.NewTree(c.Repo, entry.Id), PagesDir, func(name string, entry *git.TreeEntry) {
+		if file, _ := pageName(name); strings.Contains(file, "/") {
+			return
+		}
+
+		page, ok := c.buildArticle(commit, histories, tree, site, diagnostics, name, entry)
+		if !ok {
+			return
+		}
+
+		if page.Language != site.Language {
+			diagnostics.Add(SeverityWarning, name, "Pages can not be translated, the page was dropped", nil)
+			return
+		}
+
+		page.Name, ok = pageName(page.Name)
+		if !ok || page.Name == "" || strings.Contains(page.Name, "/") {
+			diagnostics.Add(SeverityWarning, name, "Page slug must be a single path segment, the page was dropped", nil)
+			return
+		}
+		page.Section = ""
+
+		logrus.
+			WithField("commit", commit.Id.String()).
+			WithField("page", page.Name).
+			Info("Page cached")
+
+		pages = append(pages, page)
+	})
+	if err != nil {
+		logrus.WithError(err).Warn("Pages directory could not be read")
+		diagnostics.Add(SeverityError, PagesDir, "Pages directory could not be read", err)
+	}
+
+	sort.Sort(pages)
+
+	return pages
+}
+
+// buildAuthors creates a page for every author and contributor, bios are
+// read from authors/<id>.md if present
+func (c *Cache) buildAuthors(tree *git.Tree, site *Site, diagnostics *Diagnostics, index Index) map[string]*AuthorPage {
+	authors := make(map[string]*AuthorPage)
+
+	page := func(author *Author) *AuthorPage {
+		if p, ok := authors[author.ID]; ok {
+			return p
+		}
+		p := &AuthorPage{Author: author}
+		authors[author.ID] = p
+		return p
+	}
+
+	for _, article := range index {
+		if article.Author != nil {
+			p := page(article.Author)
+			p.Articles = append(p.Articles, article)
+		}
+
+		for _, contributor := range article.Contributors {
+			if article.Author == nil || article.Author.ID != contributor.ID {
+				p := page(contributor)
+				p.Contributions = append(p.Contributions, article)
+			}
+		}
+	}
+
+	renderer, _ := c.Renderers.Get(".md")
+
+	for id, p := range authors {
+		name := "authors/" + id + ".md"
+
+		markdown, err := readBlob(tree, name)
+		if err != nil {
+			continue
+		}
+
+		_, content, err := SplitFrontMatter(markdown)
+		if err != nil {
+			logrus.
+				WithError(err).
+				WithField("author", id).
+				Warn("Front matter could not be parsed")
+
+			diagnostics.Add(SeverityWarning, name, "Front matter could not be parsed", err)
+		}
+
+		p.Bio, err = renderer.Render(&RenderContext{Tree: tree, Path: name, Site: site, Diagnostics: diagnostics}, content)
+		if err != nil {
+			logrus.
+				WithError(err).
+				WithField("author", id).
+				Warn("Bio could not be rendered")
+
+			diagnostics.Add(SeverityWarning, name, "Bio could not be rendered", err)
+		}
+	}
+
+	return authors
+}
+
+// walk calls fn for every blob in the tree, descending into directories
+func (c *Cache) walk(tree *git.Tree, dir string, fn func(string, *git.TreeEntry)) error {
+	scanner, err := tree.Scanner()
+	if err != nil {
+		return err
+	}
+
+	for scanner.Scan() {
+		entry := scanner.TreeEntry()
+		name := path.Join(dir, entry.Name())
+
+		if entry.IsDir() {
+			if err := c.walk(git.NewTree(c.Repo, entry.Id), name, fn); err != nil {
+				logrus.
+					WithError(err).
+					WithField("directory", name).
+					Warn("Directory could not be read")
+			}
+
+			continue
+		}
+
+		fn(name, entry)
+	}
+
+	return scanner.Err()
+}
+
+func (c *Cache) buildArticle(commit *git.Commit, histories Histories, tree *git.Tree, site *Site, diagnostics *Diagnostics, name string, entry *git.TreeEntry) (*Article, bool) {
+	id := commit.Id.String()
+	tid := tree.Id.String()
+
+	ext := path.Ext(name)
+
+	renderer, ok := c.Renderers.Get(ext)
+	if !ok {
+		logrus.
+			WithField("tree", tid).
+			WithField("filename", name).
+			Info("File without renderer ignored")
+
+		return nil, false
+	}
+
+	reader, err := entry.Blob().Data()
+	if err != nil {
+		logrus.
+			WithError(err).
+			WithField("tree", tid).
+			WithField("filename", name).
+			Warn("File blob could not be generated")
+
+		diagnostics.Add(SeverityError, name, "File could not be read, the article was dropped", err)
+
+		return nil, false
+	}
+
+	source, err := ioutil.ReadAll(reader)
+	if err != nil {
+		logrus.
+			WithError(err).
+			WithField("tree", tid).
+			WithField("filename", name).
+			Warn("File blob could not be read")
+
+		diagnostics.Add(SeverityError, name, "File could not be read, the article was dropped", err)
+
+		return nil, false
+	}
+
+	if _, ok := renderer.(FrontMatterRenderer); ok && !HasFrontMatter(source) {
+		logrus.
+			WithField("tree", tid).
+			WithField("filename", name).
+			Info("File without front matter ignored")
+
+		return nil, false
+	}
+
+	history := histories[name]
+	if len(history) == 0 {
+		logrus.
+			WithField("commit", id).
+			WithField("filename", name).
+			Warn("Could not get file history")
+
+		diagnostics.Add(SeverityError, name, "Could not get file history, the article was dropped", nil)
+
+		return nil, false
+	}
+
+	if history[0].Committer == nil {
+		logrus.
+			WithError(err).
+			WithField("commit", history[0].Id.String()).
+			Warn("Committer information not set")
+
+		diagnostics.Add(SeverityError, name, "Commit "+history[0].Id.String()+" has no committer, the article was dropped", nil)
+
+		return nil, false
+	}
+
+	meta, content, err := SplitFrontMatter(source)
+	if err != nil {
+		logrus.
+			WithError(err).
+			WithField("tree", tid).
+			WithField("filename", name).
+			Warn("Front matter could not be parsed")
+
+		diagnostics.Add(SeverityWarning, name, "Front matter could not be parsed", err)
+	}
+
+	ctx := &RenderContext{Tree: tree, Path: name, Site: site, Diagnostics: diagnostics}
+	data, err := renderer.Render(ctx, content)
+	if err != nil {
+		logrus.
+			WithError(err).
+			WithField("tree", tid).
+			WithField("filename", name).
+			Warn("File could not be rendered")
+
+		diagnostics.Add(SeverityError, name, "File could not be rendered, the article was dropped", err)
+
+		return nil, false
+	}
+
+	section := path.Dir(name)
+	if section == "." {
+		section = ""
+	}
+
+	base, lang := site.splitLanguage(strings.TrimSuffix(name, ext))
+
+	article := &Article{
+		Name:     base,
+		Path:     name,
+		Section:  section,
+		Language: lang,
+		Data:     data,
+		TOC:      NewTOC(ctx.Headings),
+		prefix:   site.languagePrefix(lang),
+	}
+
+	article.ApplyHistory(history)
+	article.ApplyFrontMatter(meta, site.Location())
+
+	return article, true
+}
+
+func readBlob(tree *git.Tree, path string) ([]byte, error) {
+	blob, err := tree.GetBlobByPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	reader, err := blob.Data()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	return ioutil.ReadAll(reader)
+}
@@ -0,0 +1,123 @@
+package blog
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// Diagnostic severities
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Diagnostic is a problem found while building a commit, such as a template
+// that does not parse or an article that was dropped. Line is 0 when the
+// problem is not on a particular line
+type Diagnostic struct {
+	File     string `json:"file"`
+	Line     int    `json:"line,omitempty"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// NewDiagnostic creates a diagnostic for a file, the line is read from the
+// error when it mentions one
+func NewDiagnostic(severity string, file string, message string, err error) *Diagnostic {
+	d := &Diagnostic{
+		File:     file,
+		Severity: severity,
+		Message:  message,
+	}
+
+	if err != nil {
+		d.Line = errorLine(err)
+		d.Message = fmt.Sprintf("%s: %s", message, err)
+	}
+
+	return d
+}
+
+// Error implements error, so diagnostics can be returned by the functions
+// that find them
+func (d *Diagnostic) Error() string {
+	if d.Line > 0 {
+		return fmt.Sprintf("%s:%d: %s", d.File, d.Line, d.Message)
+	}
+	return fmt.Sprintf("%s: %s", d.File, d.Message)
+}
+
+// Diagnostics lists the problems found while building a commit
+type Diagnostics []*Diagnostic
+
+// Add records a problem with a file, errors that are already diagnostics are
+// recorded as they are
+func (d *Diagnostics) Add(severity string, file string, message string, err error) {
+	if d == nil {
+		return
+	}
+
+	if diagnostic, ok := err.(*Diagnostic); ok {
+		*d = append(*d, diagnostic)
+		return
+	}
+
+	*d = append(*d, NewDiagnostic(severity, file, message, err))
+}
+
+// For gets the diagnostics for a file
+func (d Diagnostics) For(file string) Diagnostics {
+	var diagnostics Diagnostics
+	for _, diagnostic := range d {
+		if diagnostic.File == file {
+			diagnostics = append(diagnostics, diagnostic)
+		}
+	}
+	return diagnostics
+}
+
+// Errors counts the diagnostics with error severity
+func (d Diagnostics) Errors() int {
+	count := 0
+	for _, diagnostic := range d {
+		if diagnostic.Severity == SeverityError {
+			count++
+		}
+	}
+	return count
+}
+
+// Len implements sort.Interface.Len
+func (d Diagnostics) Len() int {
+	return len(d)
+}
+
+// Less implements sort.Interface.Less
+func (d Diagnostics) Less(i, j int) bool {
+	if d[i].File != d[j].File {
+		return d[i].File < d[j].File
+	}
+	return d[i].Line < d[j].Line
+}
+
+// Swap implements sort.Interface.Swap
+func (d Diagnostics) Swap(i, j int) {
+	d[i], d[j] = d[j], d[i]
+}
+
+// errorLinePatterns find line numbers in template and front matter errors
+var errorLinePatterns = []*regexp.Regexp{
+	regexp.MustCompile(`^template: [^:]+:(\d+):`),
+	regexp.MustCompile(`on line (\d+)`),
+}
+
+func errorLine(err error) int {
+	for _, pattern := range errorLinePatterns {
+		if match := pattern.FindStringSubmatch(err.Error()); match != nil {
+			line, _ := strconv.Atoi(match[1])
+			return line
+		}
+	}
+	return 0
+}
@@ -0,0 +1,233 @@
+package blog
+
+import (
+	"strings"
+)
+
+// DiffOp is the type of change a diff line represents
+type DiffOp int
+
+// Diff operations
+const (
+	DiffEqual DiffOp = iota
+	DiffInsert
+	DiffDelete
+)
+
+// DiffLine is a single line in a diff, Old and New are the line numbers in
+// each version or 0 if the line is not present in that version
+type DiffLine struct {
+	Op   DiffOp
+	Text string
+	Old  int
+	New  int
+}
+
+// Prefix is the unified diff prefix for the line
+func (l *DiffLine) Prefix() string {
+	switch l.Op {
+	case DiffInsert:
+		return "+"
+	case DiffDelete:
+		return "-"
+	}
+	return " "
+}
+
+// Class is the css class for the line
+func (l *DiffLine) Class() string {
+	switch l.Op {
+	case DiffInsert:
+		return "diff__line diff__line--insert"
+	case DiffDelete:
+		return "diff__line diff__line--delete"
+	}
+	return "diff__line"
+}
+
+// DiffRow is a row of a side by side diff, either side may be nil
+type DiffRow struct {
+	Left  *DiffLine
+	Right *DiffLine
+}
+
+// DiffLines splits text into lines for diffing
+func DiffLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// MaxDiffLines is the most lines, counting both versions, that are diffed
+const MaxDiffLines = 10000
+
+// Diff computes a line diff between a and b using the linear space variant of
+// the Myers algorithm, the edit script is found by splitting the problem at
+// the middle snake of the shortest edit path
+func Diff(a, b []string) []DiffLine {
+	size := 2*(len(a)+len(b)) + 3
+
+	d := &differ{
+		a:        a,
+		b:        b,
+		forward:  make([]int, size),
+		backward: make([]int, size),
+	}
+	d.diff(0, len(a), 0, len(b))
+
+	return d.lines
+}
+
+type differ struct {
+	a, b              []string
+	forward, backward []int
+	lines             []DiffLine
+}
+
+func (d *differ) equal(x, y int) {
+	d.lines = append(d.lines, DiffLine{Op: DiffEqual, Text: d.a[x], Old: x + 1, New: y + 1})
+}
+
+// replace deletes a[aLo:aHi] and inserts b[bLo:bHi]
+func (d *differ) replace(aLo, aHi, bLo, bHi int) {
+	for x := aLo; x < aHi; x++ {
+		d.lines = append(d.lines, DiffLine{Op: DiffDelete, Text: d.a[x], Old: x + 1})
+	}
+	for y := bLo; y < bHi; y++ {
+		d.lines = append(d.lines, DiffLine{Op: DiffInsert, Text: d.b[y], New: y + 1})
+	}
+}
+
+// diff appends the edit script for a[aLo:aHi] and b[bLo:bHi]
+func (d *differ) diff(aLo, aHi, bLo, bHi int) {
+	for aLo < aHi && bLo < bHi && d.a[aLo] == d.b[bLo] {
+		d.equal(aLo, bLo)
+		aLo++
+		bLo++
+	}
+
+	suffix := 0
+	for aLo < aHi-suffix && bLo < bHi-suffix && d.a[aHi-suffix-1] == d.b[bHi-suffix-1] {
+		suffix++
+	}
+	aHi, bHi = aHi-suffix, bHi-suffix
+
+	if aLo < aHi && bLo < bHi {
+		if x, y, u, v, ok := d.middleSnake(aLo, aHi, bLo, bHi); ok {
+			d.diff(aLo, x, bLo, y)
+			for ; x < u; x, y = x+1, y+1 {
+				d.equal(x, y)
+			}
+			d.diff(u, aHi, v, bHi)
+		} else {
+			// The range is replaced as a whole rather than failing the diff,
+			// the script is still correct but may not be the shortest
+			d.replace(aLo, aHi, bLo, bHi)
+		}
+	} else {
+		d.replace(aLo, aHi, bLo, bHi)
+	}
+
+	for i := 0; i < suffix; i++ {
+		d.equal(aHi+i, bHi+i)
+	}
+}
+
+// middleSnake finds the snake in the middle of the shortest edit path by
+// searching forwards from the start and backwards from the end until the
+// paths overlap, the snake runs from x, y to u, v. The paths always overlap
+// by the time half of the edits are made, ok is false should they not
+func (d *differ) middleSnake(aLo, aHi, bLo, bHi int) (x, y, u, v int, ok bool) {
+	n, m := aHi-aLo, bHi-bLo
+	delta := n - m
+	odd := delta%2 != 0
+	limit := (n + m + 1) / 2
+	offset := limit + 1
+
+	forward, backward := d.forward, d.backward
+	forward[offset+1], backward[offset+1] = 0, 0
+
+	for depth := 0; depth <= limit; depth++ {
+		for k := -depth; k <= depth; k += 2 {
+			var x int
+			if k == -depth || (k != depth && forward[offset+k-1] < forward[offset+k+1]) {
+				x = forward[offset+k+1]
+			} else {
+				x = forward[offset+k-1] + 1
+			}
+
+			y := x - k
+			startX, startY := x, y
+			for x < n && y < m && d.a[aLo+x] == d.b[bLo+y] {
+				x++
+				y++
+			}
+			forward[offset+k] = x
+
+			// The backward path on the same diagonal is delta - k
+			if r := delta - k; odd && r >= -(depth-1) && r <= depth-1 && x+backward[offset+r] >= n {
+				return aLo + startX, bLo + startY, aLo + x, bLo + y, true
+			}
+		}
+
+		for k := -depth; k <= depth; k += 2 {
+			var x int
+			if k == -depth || (k != depth && backward[offset+k-1] < backward[offset+k+1]) {
+				x = backward[offset+k+1]
+			} else {
+				x = backward[offset+k-1] + 1
+			}
+
+			y := x - k
+			startX, startY := x, y
+			for x < n && y < m && d.a[aHi-x-1] == d.b[bHi-y-1] {
+				x++
+				y++
+			}
+			backward[offset+k] = x
+
+			if f := delta - k; !odd && f >= -depth && f <= depth && x+forward[offset+f] >= n {
+				return aHi - x, bHi - y, aHi - startX, bHi - startY, true
+			}
+		}
+	}
+
+	return 0, 0, 0, 0, false
+}
+
+// SideBySide pairs deleted and inserted lines into rows for a side by side
+// view
+func SideBySide(lines []DiffLine) []DiffRow {
+	var rows []DiffRow
+
+	for i := 0; i < len(lines); {
+		if lines[i].Op == DiffEqual {
+			rows = append(rows, DiffRow{Left: &lines[i], Right: &lines[i]})
+			i++
+			continue
+		}
+
+		var deleted, inserted []*DiffLine
+		for ; i < len(lines) && lines[i].Op != DiffEqual; i++ {
+			if lines[i].Op == DiffDelete {
+				deleted = append(deleted, &lines[i])
+			} else {
+				inserted = append(inserted, &lines[i])
+			}
+		}
+
+		for j := 0; j < len(deleted) || j < len(inserted); j++ {
+			var row DiffRow
+			if j < len(deleted) {
+				row.Left = deleted[j]
+			}
+			if j < len(inserted) {
+				row.Right = inserted[j]
+			}
+			rows = append(rows, row)
+		}
+	}
+
+	return rows
+}
@@ -0,0 +1,136 @@
+package blog
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		text  string
+		lines []string
+	}{
+		{"", nil},
+		{"a", []string{"a"}},
+		{"a\n", []string{"a"}},
+		{"a\n\nb\n", []string{"a", "", "b"}},
+	}
+
+	for _, test := range tests {
+		if lines := DiffLines(test.text); !reflect.DeepEqual(lines, test.lines) {
+			t.Errorf("%q: expected %q, got %q", test.text, test.lines, lines)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		diff string
+	}{
+		{"both empty", "", "", ""},
+		{"empty to text", "", "a\nb", "+a +b"},
+		{"text to empty", "a\nb", "", "-a -b"},
+		{"identical", "a\nb\nc", "a\nb\nc", " a  b  c"},
+		{"insert", "a\nc", "a\nb\nc", " a +b  c"},
+		{"delete", "a\nb\nc", "a\nc", " a -b  c"},
+		{"replace", "a\nb\nc", "a\nx\nc", " a -b +x  c"},
+		{"disjoint", "a\nb", "c\nd", "-a -b +c +d"},
+	}
+
+	for _, test := range tests {
+		var ops []string
+		for _, line := range Diff(DiffLines(test.a), DiffLines(test.b)) {
+			ops = append(ops, line.Prefix()+line.Text)
+		}
+
+		if diff := strings.Join(ops, " "); diff != test.diff {
+			t.Errorf("%s: expected %q, got %q", test.name, test.diff, diff)
+		}
+	}
+}
+
+func TestDifferReplace(t *testing.T) {
+	d := &differ{a: []string{"a", "b", "c"}, b: []string{"x", "y"}}
+	d.replace(1, 3, 0, 1)
+
+	expected := []DiffLine{
+		{Op: DiffDelete, Text: "b", Old: 2},
+		{Op: DiffDelete, Text: "c", Old: 3},
+		{Op: DiffInsert, Text: "x", New: 1},
+	}
+	if !reflect.DeepEqual(d.lines, expected) {
+		t.Errorf("expected %+v, got %+v", expected, d.lines)
+	}
+}
+
+func TestDiffIsMinimal(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	words := []string{"a", "b", "c", "d"}
+
+	lines := func() []string {
+		l := make([]string, random.Intn(30))
+		for i := range l {
+			l[i] = words[random.Intn(len(words))]
+		}
+		return l
+	}
+
+	for i := 0; i < 500; i++ {
+		a, b := lines(), lines()
+		diff := Diff(a, b)
+
+		var old, new []string
+		edits := 0
+		for _, line := range diff {
+			if line.Op != DiffInsert {
+				old = append(old, line.Text)
+				if line.Old != len(old) {
+					t.Fatalf("%q -> %q: line %q has old number %d, expected %d", a, b, line.Text, line.Old, len(old))
+				}
+			}
+			if line.Op != DiffDelete {
+				new = append(new, line.Text)
+				if line.New != len(new) {
+					t.Fatalf("%q -> %q: line %q has new number %d, expected %d", a, b, line.Text, line.New, len(new))
+				}
+			}
+			if line.Op != DiffEqual {
+				edits++
+			}
+		}
+
+		if strings.Join(old, "\n") != strings.Join(a, "\n") || strings.Join(new, "\n") != strings.Join(b, "\n") {
+			t.Fatalf("%q -> %q: diff does not reproduce the inputs", a, b)
+		}
+		if expected := len(a) + len(b) - 2*lcs(a, b); edits != expected {
+			t.Fatalf("%q -> %q: expected %d edits, got %d", a, b, expected, edits)
+		}
+	}
+}
+
+// lcs is the length of the longest common subsequence of a and b
+func lcs(a, b []string) int {
+	table := make([][]int, len(a)+1)
+	for i := range table {
+		table[i] = make([]int, len(b)+1)
+	}
+
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			switch {
+			case a[i] == b[j]:
+				table[i][j] = table[i+1][j+1] + 1
+			case table[i+1][j] > table[i][j+1]:
+				table[i][j] = table[i+1][j]
+			default:
+				table[i][j] = table[i][j+1]
+			}
+		}
+	}
+
+	return table[0][0]
+}
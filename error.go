@@ -1,11 +1,75 @@
 package blog
 
 import (
-    "fmt"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
 	"github.com/ThatsMrTalbot/scaffold/errors"
 )
 
+// ErrorReponse creates an error with the given status, the message is
+// prefixed to the error text
 func ErrorReponse(status int, message string, err error) error {
-    msg := fmt.Sprintf("%s: %s", message, err.Error())
-    return errors.NewErrorStatus(500, msg)
-}
\ No newline at end of file
+	if err == nil {
+		return errors.NewErrorStatus(status, message)
+	}
+
+	msg := fmt.Sprintf("%s: %s", message, err.Error())
+	return errors.NewErrorStatus(status, msg)
+}
+
+// Problem is an RFC 7807 problem detail, it is sent instead of an error page
+// to clients that prefer json
+type Problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail,omitempty"`
+	Instance  string `json:"instance,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// errorMessage gets the message shown to the client for an error, server
+// errors are not described as they may contain details of the repository
+func errorMessage(status int, err error) string {
+	if status >= 500 || err == nil {
+		return http.StatusText(status)
+	}
+	return err.Error()
+}
+
+// prefersJSON checks if the Accept header of the request ranks a json media
+// type above html
+func prefersJSON(r *http.Request) bool {
+	var jsonQ, htmlQ float64
+
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediatype, params, err := mime.ParseMediaType(strings.TrimSpace(accept))
+		if err != nil {
+			continue
+		}
+
+		q := 1.0
+		if value, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(value, 64); err != nil {
+				continue
+			}
+		}
+
+		switch {
+		case mediatype == "application/json", mediatype == "application/problem+json":
+			if q > jsonQ {
+				jsonQ = q
+			}
+		case mediatype == "text/html", mediatype == "text/*", mediatype == "*/*":
+			if q > htmlQ {
+				htmlQ = q
+			}
+		}
+	}
+
+	return jsonQ > 0 && jsonQ > htmlQ
+}
@@ -0,0 +1,376 @@
+package blog
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FrontMatter is the metadata block found at the top of an article, it is
+// delimited by --- for YAML or +++ for TOML
+type FrontMatter map[string]interface{}
+
+var frontMatterDelimiters = map[string]string{
+	"---": "yaml",
+	"+++": "toml",
+}
+
+var frontMatterDateFormats = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// HasFrontMatter checks if data starts with a front matter delimiter
+func HasFrontMatter(data []byte) bool {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	end := bytes.IndexByte(data, '\n')
+	if end < 0 {
+		return false
+	}
+
+	_, ok := frontMatterDelimiters[string(bytes.TrimSpace(data[:end]))]
+	return ok
+}
+
+// SplitFrontMatter splits a file into its front matter and the remaining
+// content, if no front matter is present the content is returned untouched
+func SplitFrontMatter(data []byte) (FrontMatter, []byte, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	end := bytes.IndexByte(data, '\n')
+	if end < 0 {
+		return nil, data, nil
+	}
+
+	delimiter := string(bytes.TrimSpace(data[:end]))
+	format, ok := frontMatterDelimiters[delimiter]
+	if !ok {
+		return nil, data, nil
+	}
+
+	rest := data[end+1:]
+	offset := 0
+	for offset < len(rest) {
+		lineEnd := bytes.IndexByte(rest[offset:], '\n')
+		line := rest[offset:]
+		if lineEnd >= 0 {
+			line = rest[offset : offset+lineEnd]
+		}
+
+		if string(bytes.TrimSpace(line)) == delimiter {
+			content := []byte{}
+			if lineEnd >= 0 {
+				content = rest[offset+lineEnd+1:]
+			}
+
+			// The block is dropped even when it does not parse, so it is
+			// never rendered as part of the article
+			meta, err := parseMeta(format, rest[:offset], 1)
+			if err != nil {
+				return nil, content, err
+			}
+			return meta, content, nil
+		}
+
+		if lineEnd < 0 {
+			break
+		}
+		offset += lineEnd + 1
+	}
+
+	return nil, data, fmt.Errorf("Front matter opened with %s is never closed", delimiter)
+}
+
+// String gets a string value from the front matter
+func (f FrontMatter) String(key string) string {
+	switch v := f[key].(type) {
+	case string:
+		return v
+	case []string:
+		return strings.Join(v, ", ")
+	}
+	return ""
+}
+
+// Strings gets a list of strings from the front matter, a single value is
+// treated as a list of one
+func (f FrontMatter) Strings(key string) []string {
+	switch v := f[key].(type) {
+	case string:
+		if v == "" {
+			return nil
+		}
+		return []string{v}
+	case []string:
+		return v
+	}
+	return nil
+}
+
+// Bool gets a boolean value from the front matter
+func (f FrontMatter) Bool(key string) bool {
+	b, _ := strconv.ParseBool(f.String(key))
+	return b
+}
+
+// Time gets a date from the front matter, ok is false if the key is not set
+// or the date could not be parsed
+func (f FrontMatter) Time(key string) (t time.Time, ok bool) {
+	return f.TimeIn(key, time.UTC)
+}
+
+// TimeIn gets a date from the front matter, dates without an offset are read
+// in the given location
+func (f FrontMatter) TimeIn(key string, loc *time.Location) (t time.Time, ok bool) {
+	value := f.String(key)
+	if value == "" {
+		return t, false
+	}
+
+	for _, format := range frontMatterDateFormats {
+		if t, err := time.ParseInLocation(format, value, loc); err == nil {
+			return t, true
+		}
+	}
+
+	return t, false
+}
+
+// parseMeta parses front matter or a site config, line is the number of lines
+// in the file before data so errors refer to lines of the file.
+//
+// Only a subset of YAML and TOML is supported, anything outside of it is an
+// error rather than being misread:
+//
+//	key: value, key = value      strings, numbers, booleans and dates
+//	key: [a, "b, c"]             flow lists, quoted items may contain commas
+//	key:\n  - a\n  - b          YAML block lists
+//	key: |, key: >               YAML literal and folded block scalars
+//	key = [\n  "a",\n  "b",\n]     TOML arrays spanning several lines
+//	[table]                      TOML tables, keys become table.key
+//
+// Nested YAML maps, inline tables and multi-line TOML strings are rejected
+func parseMeta(format string, data []byte, line int) (FrontMatter, error) {
+	meta := make(FrontMatter)
+	lines := strings.Split(strings.Replace(string(data), "\r\n", "\n", -1), "\n")
+
+	var listKey, table string
+
+	for i := 0; i < len(lines); i++ {
+		number := line + i + 1
+		text := strings.TrimRight(lines[i], " \t\r")
+		trimmed := strings.TrimSpace(text)
+
+		if trimmed == "" || trimmed[0] == '#' {
+			continue
+		}
+
+		indented := text[0] == ' ' || text[0] == '\t'
+
+		// YAML block lists continue the previous key
+		if format == "yaml" && listKey != "" && (trimmed == "-" || strings.HasPrefix(trimmed, "- ")) {
+			item := unquoteMeta(stripMetaComment(strings.TrimSpace(strings.TrimPrefix(trimmed, "-"))))
+			list, _ := meta[listKey].([]string)
+			meta[listKey] = append(list, item)
+			continue
+		}
+
+		if format == "yaml" && indented {
+			return nil, fmt.Errorf("Nested %s maps are not supported on line %d: %q", format, number, trimmed)
+		}
+		listKey = ""
+
+		// TOML tables prefix the keys that follow them
+		if header := stripMetaComment(trimmed); format == "toml" && strings.HasPrefix(header, "[") && strings.HasSuffix(header, "]") {
+			table = strings.TrimSpace(header[1:len(header)-1]) + "."
+			continue
+		}
+
+		separator := ":"
+		if format == "toml" {
+			separator = "="
+		}
+
+		index := strings.Index(trimmed, separator)
+		if index <= 0 {
+			return nil, fmt.Errorf("Invalid %s on line %d: %q", format, number, trimmed)
+		}
+
+		key := table + unquoteMeta(strings.TrimSpace(trimmed[:index]))
+		value := stripMetaComment(strings.TrimSpace(trimmed[index+1:]))
+
+		// TOML arrays may span several lines until the closing bracket
+		if format == "toml" && strings.HasPrefix(value, "[") {
+			for !strings.HasSuffix(value, "]") {
+				i++
+				if i >= len(lines) {
+					return nil, fmt.Errorf("Unclosed %s array on line %d", format, number)
+				}
+				value += " " + stripMetaComment(strings.TrimSpace(lines[i]))
+			}
+		}
+
+		switch {
+		case value == "" && format == "yaml":
+			listKey = key
+			meta[key] = []string(nil)
+		case format == "yaml" && isBlockScalar(value):
+			var block []string
+			for i+1 < len(lines) && (strings.TrimSpace(lines[i+1]) == "" || lines[i+1][0] == ' ' || lines[i+1][0] == '\t') {
+				i++
+				block = append(block, lines[i])
+			}
+			meta[key] = blockScalar(value, block)
+		case strings.HasPrefix(value, "{"):
+			return nil, fmt.Errorf("Inline %s tables are not supported on line %d: %q", format, number, trimmed)
+		case strings.HasPrefix(value, `"""`), strings.HasPrefix(value, "'''"):
+			return nil, fmt.Errorf("Multi-line %s strings are not supported on line %d: %q", format, number, trimmed)
+		case strings.HasPrefix(value, "["):
+			if !strings.HasSuffix(value, "]") {
+				return nil, fmt.Errorf("Unclosed %s list on line %d: %q", format, number, trimmed)
+			}
+			meta[key] = splitMetaList(value[1 : len(value)-1])
+		default:
+			meta[key] = unquoteMeta(value)
+		}
+	}
+
+	return meta, nil
+}
+
+// isBlockScalar checks for the YAML block scalar indicators | and >, with an
+// optional chomping indicator
+func isBlockScalar(value string) bool {
+	switch value {
+	case "|", "|-", "|+", ">", ">-", ">+":
+		return true
+	}
+	return false
+}
+
+// blockScalar joins the indented lines of a YAML block scalar, literal
+// scalars keep their line breaks and folded scalars join lines with spaces
+func blockScalar(indicator string, lines []string) string {
+	indent := -1
+	for _, l := range lines {
+		if strings.TrimSpace(l) == "" {
+			continue
+		}
+		if n := len(l) - len(strings.TrimLeft(l, " \t")); indent < 0 || n < indent {
+			indent = n
+		}
+	}
+
+	var out bytes.Buffer
+	for i, l := range lines {
+		l = strings.TrimRight(l, " \t\r")
+		if len(l) >= indent && indent > 0 {
+			l = l[indent:]
+		} else {
+			l = strings.TrimSpace(l)
+		}
+
+		switch {
+		case indicator[0] == '|':
+			if i > 0 {
+				out.WriteByte('\n')
+			}
+		case l == "":
+			// Blank lines in folded scalars are kept as line breaks
+			out.WriteByte('\n')
+		case i > 0 && strings.TrimSpace(lines[i-1]) != "":
+			out.WriteByte(' ')
+		}
+		out.WriteString(l)
+	}
+
+	value := strings.TrimRight(out.String(), "\n")
+	switch {
+	case strings.HasSuffix(indicator, "-"):
+		return value
+	case strings.HasSuffix(indicator, "+"):
+		return out.String() + "\n"
+	}
+	return value + "\n"
+}
+
+// splitMetaList splits the items of a flow list, commas inside quoted items
+// do not separate items
+func splitMetaList(value string) []string {
+	var list []string
+	var item []rune
+	var quote rune
+
+	add := func() {
+		if s := unquoteMeta(strings.TrimSpace(string(item))); s != "" {
+			list = append(list, s)
+		}
+		item = item[:0]
+	}
+
+	for _, r := range value {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == ',':
+			add()
+			continue
+		}
+		item = append(item, r)
+	}
+	add()
+
+	return list
+}
+
+// stripMetaComment removes a trailing # comment, a # inside a quoted string
+// does not start a comment
+func stripMetaComment(value string) string {
+	var quote byte
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || value[i-1] == ' ' || value[i-1] == '\t'):
+			return strings.TrimSpace(value[:i])
+		}
+	}
+	return value
+}
+
+func unquoteMeta(value string) string {
+	if len(value) < 2 {
+		return value
+	}
+
+	switch value[0] {
+	case '"':
+		if s, err := strconv.Unquote(value); err == nil {
+			return s
+		}
+		return strings.Trim(value, `"`)
+	case '\'':
+		if value[len(value)-1] == '\'' {
+			return strings.Replace(value[1:len(value)-1], "''", "'", -1)
+		}
+	}
+
+	return value
+}
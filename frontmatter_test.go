@@ -0,0 +1,198 @@
+package blog
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitFrontMatter(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		meta    FrontMatter
+		content string
+		err     string
+	}{
+		{
+			name:    "no front matter",
+			data:    "# Title\n\nBody\n",
+			content: "# Title\n\nBody\n",
+		},
+		{
+			name:    "yaml",
+			data:    "---\ntitle: Hello\ndraft: true\n---\nBody\n",
+			meta:    FrontMatter{"title": "Hello", "draft": "true"},
+			content: "Body\n",
+		},
+		{
+			name:    "toml",
+			data:    "+++\ntitle = \"Hello\"\n+++\nBody\n",
+			meta:    FrontMatter{"title": "Hello"},
+			content: "Body\n",
+		},
+		{
+			name:    "byte order mark",
+			data:    "\xef\xbb\xbf---\ntitle: Hello\n---\nBody",
+			meta:    FrontMatter{"title": "Hello"},
+			content: "Body",
+		},
+		{
+			name:    "closed at end of file",
+			data:    "---\ntitle: Hello\n---",
+			meta:    FrontMatter{"title": "Hello"},
+			content: "",
+		},
+		{
+			name:    "unclosed",
+			data:    "---\ntitle: Hello\nBody\n",
+			content: "---\ntitle: Hello\nBody\n",
+			err:     "never closed",
+		},
+		{
+			name:    "invalid drops the block",
+			data:    "---\ntitle Hello\n---\nBody\n",
+			content: "Body\n",
+			err:     "on line 2",
+		},
+	}
+
+	for _, test := range tests {
+		meta, content, err := SplitFrontMatter([]byte(test.data))
+
+		if test.err == "" && err != nil {
+			t.Errorf("%s: unexpected error %v", test.name, err)
+		}
+		if test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)) {
+			t.Errorf("%s: expected error containing %q, got %v", test.name, test.err, err)
+		}
+		if !reflect.DeepEqual(meta, test.meta) {
+			t.Errorf("%s: expected meta %#v, got %#v", test.name, test.meta, meta)
+		}
+		if string(content) != test.content {
+			t.Errorf("%s: expected content %q, got %q", test.name, test.content, content)
+		}
+	}
+}
+
+func TestParseMeta(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		data   string
+		meta   FrontMatter
+		err    string
+	}{
+		{
+			name:   "yaml flow list with quoted commas",
+			format: "yaml",
+			data:   "tags: [go, \"a, b\", 'c']",
+			meta:   FrontMatter{"tags": []string{"go", "a, b", "c"}},
+		},
+		{
+			name:   "yaml block list",
+			format: "yaml",
+			data:   "tags:\n  - go\n  - \"blog\" # comment",
+			meta:   FrontMatter{"tags": []string{"go", "blog"}},
+		},
+		{
+			name:   "yaml literal block scalar",
+			format: "yaml",
+			data:   "summary: |\n  one\n  two\ntitle: Hello",
+			meta:   FrontMatter{"summary": "one\ntwo\n", "title": "Hello"},
+		},
+		{
+			name:   "yaml folded block scalar",
+			format: "yaml",
+			data:   "summary: >-\n  one\n  two\n\n  three",
+			meta:   FrontMatter{"summary": "one two\nthree"},
+		},
+		{
+			name:   "yaml nested map",
+			format: "yaml",
+			data:   "author:\n  name: Bob",
+			err:    "Nested yaml maps are not supported on line 3",
+		},
+		{
+			name:   "yaml flow map",
+			format: "yaml",
+			data:   "author: {name: Bob}",
+			err:    "Inline yaml tables are not supported on line 2",
+		},
+		{
+			name:   "toml table",
+			format: "toml",
+			data:   "[params]\ncolor = \"red\" # comment",
+			meta:   FrontMatter{"params.color": "red"},
+		},
+		{
+			name:   "toml multi-line array",
+			format: "toml",
+			data:   "tags = [\n  \"go\",\n  \"blog\",\n]\ntitle = \"Hello\"",
+			meta:   FrontMatter{"tags": []string{"go", "blog"}, "title": "Hello"},
+		},
+		{
+			name:   "toml unclosed array",
+			format: "toml",
+			data:   "tags = [\n  \"go\",",
+			err:    "Unclosed toml array on line 2",
+		},
+		{
+			name:   "toml inline table",
+			format: "toml",
+			data:   "author = { name = \"Bob\" }",
+			err:    "Inline toml tables are not supported on line 2",
+		},
+		{
+			name:   "toml multi-line string",
+			format: "toml",
+			data:   "summary = \"\"\"\nText\n\"\"\"",
+			err:    "Multi-line toml strings are not supported on line 2",
+		},
+		{
+			name:   "missing separator",
+			format: "toml",
+			data:   "title: Hello",
+			err:    "Invalid toml on line 2",
+		},
+	}
+
+	for _, test := range tests {
+		meta, err := parseMeta(test.format, []byte(test.data), 1)
+
+		if test.err == "" && err != nil {
+			t.Errorf("%s: unexpected error %v", test.name, err)
+			continue
+		}
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("%s: expected error containing %q, got %v", test.name, test.err, err)
+			}
+			continue
+		}
+		if !reflect.DeepEqual(meta, test.meta) {
+			t.Errorf("%s: expected %#v, got %#v", test.name, test.meta, meta)
+		}
+	}
+}
+
+func TestHasFrontMatter(t *testing.T) {
+	tests := []struct {
+		data string
+		has  bool
+	}{
+		{"", false},
+		{"---", false},
+		{"---\ntitle: a\n---\n", true},
+		{"+++\ntitle = 'a'\n+++\n", true},
+		{"\xef\xbb\xbf---\n", true},
+		{"User-agent: *\n", false},
+		{"<p>---</p>\n", false},
+	}
+
+	for _, test := range tests {
+		if has := HasFrontMatter([]byte(test.data)); has != test.has {
+			t.Errorf("%q: expected %v, got %v", test.data, test.has, has)
+		}
+	}
+}
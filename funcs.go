@@ -0,0 +1,172 @@
+package blog
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// templateFuncs are the helpers available to every template, site is used
+// when rendering markdown, index and nav are the articles and pages in the
+// commit the templates belong to. The helpers are:
+//
+//	date "2 January 2006" t   formats a time in the site timezone
+//	ago t                     describes a time relative to now, e.g. 3 days ago
+//	articleURL base article   url of an article, by *Article, Article or name
+//	tagURL base name          url of a tag
+//	categoryURL base name     url of a category
+//	pageURL base name         url of a page
+//	markdown s                renders a markdown string
+//	truncate n s              cuts a string to n characters
+//	slugify s                 converts a string into a url slug
+//	json v                    encodes a value as json
+//	articles                  the published articles, newest first
+//	pages                     the published pages, in navigation order
+//
+// The base of the url helpers is the BaseURL of the model, for example
+// {{articleURL .BaseURL .Article}}
+func templateFuncs(site *Site, index Index, nav Pages) template.FuncMap {
+	return template.FuncMap{
+		"date": func(layout string, t time.Time) string {
+			return formatDate(layout, t.In(site.Location()))
+		},
+		"ago":         relativeTime,
+		"articleURL":  articleURL,
+		"tagURL":      termURL("tags"),
+		"categoryURL": termURL("categories"),
+		"pageURL":     pageURL,
+		"markdown": func(s string) (template.HTML, error) {
+			data, err := renderMarkdown(&RenderContext{Site: site}, []byte(s))
+			return template.HTML(data), err
+		},
+		"truncate": truncate,
+		"slugify":  Slugify,
+		"json":     jsonEncode,
+		"articles": func() Index {
+			return index.Published(time.Now())
+		},
+		"pages": func() Pages {
+			return nav.Published(time.Now())
+		},
+	}
+}
+
+// formatDate formats a time using a Go time layout
+func formatDate(layout string, t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(layout)
+}
+
+var relativeUnits = []struct {
+	name     string
+	duration time.Duration
+}{
+	{"year", 365 * 24 * time.Hour},
+	{"month", 30 * 24 * time.Hour},
+	{"week", 7 * 24 * time.Hour},
+	{"day", 24 * time.Hour},
+	{"hour", time.Hour},
+	{"minute", time.Minute},
+}
+
+// relativeTime describes a time relative to now in the largest whole unit,
+// for example 2 hours ago or 1 day from now
+func relativeTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	d := time.Since(t)
+	suffix := "ago"
+	if d < 0 {
+		d = -d
+		suffix = "from now"
+	}
+
+	for _, unit := range relativeUnits {
+		if n := int(d / unit.duration); n > 0 {
+			if n == 1 {
+				return fmt.Sprintf("1 %s %s", unit.name, suffix)
+			}
+			return fmt.Sprintf("%d %ss %s", n, unit.name, suffix)
+		}
+	}
+
+	return "just now"
+}
+
+// articleURL builds the url of an article under the base url
+func articleURL(base *url.URL, article interface{}) (string, error) {
+	var link string
+	switch a := article.(type) {
+	case *Article:
+		link = a.Link()
+	case Article:
+		link = a.Link()
+	case string:
+		link = "article/" + a + "/"
+	default:
+		return "", fmt.Errorf("articleURL: unexpected %T", article)
+	}
+
+	return resolveURL(base, link)
+}
+
+// termURL builds a helper for the url of a tag or category under the base url
+func termURL(taxonomy string) func(base *url.URL, name string) (string, error) {
+	return func(base *url.URL, name string) (string, error) {
+		return resolveURL(base, taxonomy+"/"+Slugify(name)+"/")
+	}
+}
+
+// pageURL builds the url of a page under the base url
+func pageURL(base *url.URL, name string) (string, error) {
+	name = strings.Trim(name, "/")
+	if name == "" {
+		return resolveURL(base, "")
+	}
+	return resolveURL(base, name+"/")
+}
+
+func resolveURL(base *url.URL, ref string) (string, error) {
+	if base == nil {
+		return "", fmt.Errorf("no base url")
+	}
+
+	u, err := base.Parse(ref)
+	if err != nil {
+		return "", err
+	}
+
+	return u.String(), nil
+}
+
+// truncate cuts a string to at most n characters, breaking on a space where
+// possible and adding an ellipsis when cut
+func truncate(n int, s string) string {
+	s = strings.TrimSpace(s)
+	if n <= 0 || utf8.RuneCountInString(s) <= n {
+		return s
+	}
+
+	runes := []rune(s)[:n]
+	cut := string(runes)
+	if i := strings.LastIndex(cut, " "); i > 0 {
+		cut = cut[:i]
+	}
+
+	return strings.TrimRight(cut, " ,.;:") + "…"
+}
+
+// jsonEncode encodes a value as json, it can be used directly inside a
+// script tag
+func jsonEncode(v interface{}) (template.JS, error) {
+	data, err := json.Marshal(v)
+	return template.JS(data), err
+}
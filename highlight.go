@@ -0,0 +1,463 @@
+package blog
+
+import (
+	"bytes"
+	"html"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Highlight classes, these are emitted as hl-<class> css classes
+const (
+	hlKeyword  = "keyword"
+	hlType     = "type"
+	hlString   = "string"
+	hlNumber   = "number"
+	hlComment  = "comment"
+	hlKey      = "key"
+	hlVariable = "variable"
+	hlInserted = "inserted"
+	hlDeleted  = "deleted"
+	hlMeta     = "meta"
+)
+
+type token struct {
+	class string
+	text  string
+}
+
+// language describes the syntax of a language for the highlighter
+type language struct {
+	keywords        map[string]bool
+	types           map[string]bool
+	lineComments    []string
+	blockComment    [2]string
+	quotes          string
+	rawQuotes       string
+	identChars      string
+	keys            bool
+	variables       bool
+	caseInsensitive bool
+	lines           func(line string) string
+}
+
+func words(s string) map[string]bool {
+	m := make(map[string]bool)
+	for _, w := range strings.Fields(s) {
+		m[w] = true
+	}
+	return m
+}
+
+var goLanguage = &language{
+	keywords: words(`break case chan const continue default defer else fallthrough for func go goto if
+		import interface map package range return select struct switch type var true false nil iota`),
+	types: words(`bool byte complex64 complex128 error float32 float64 int int8 int16 int32 int64 rune
+		string uint uint8 uint16 uint32 uint64 uintptr append cap close complex copy delete imag len
+		make new panic print println real recover`),
+	lineComments: []string{"//"},
+	blockComment: [2]string{"/*", "*/"},
+	quotes:       `"'`,
+	rawQuotes:    "`",
+}
+
+var shellLanguage = &language{
+	keywords: words(`if then else elif fi for while until do done case esac in function return
+		exit export local readonly set unset source alias cd echo`),
+	lineComments: []string{"#"},
+	quotes:       `"`,
+	rawQuotes:    `'`,
+	identChars:   "-",
+	variables:    true,
+}
+
+var jsonLanguage = &language{
+	keywords: words(`true false null`),
+	quotes:   `"`,
+	keys:     true,
+}
+
+var yamlLanguage = &language{
+	keywords:     words(`true false null yes no on off ~`),
+	lineComments: []string{"#"},
+	quotes:       `"`,
+	rawQuotes:    `'`,
+	identChars:   "-.",
+	keys:         true,
+}
+
+var sqlLanguage = &language{
+	keywords: words(`select from where and or not insert into values update set delete create table
+		drop alter index view join inner left right outer full on as group by order having limit
+		offset union all distinct case when then else end is null like in between exists primary
+		key foreign references default constraint unique begin commit rollback transaction with
+		returning asc desc cascade if`),
+	types: words(`int integer bigint smallint serial bigserial text varchar char boolean bool date
+		timestamp timestamptz time interval numeric decimal real float double uuid json jsonb
+		count sum avg min max coalesce now`),
+	lineComments:    []string{"--"},
+	blockComment:    [2]string{"/*", "*/"},
+	quotes:          `"'`,
+	caseInsensitive: true,
+}
+
+var diffLanguage = &language{
+	lines: func(line string) string {
+		switch {
+		case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"), strings.HasPrefix(line, "@@"):
+			return hlMeta
+		case strings.HasPrefix(line, "+"):
+			return hlInserted
+		case strings.HasPrefix(line, "-"):
+			return hlDeleted
+		}
+		return ""
+	},
+}
+
+// languages maps fenced code block language names to their syntax
+var languages = map[string]*language{
+	"go":      goLanguage,
+	"golang":  goLanguage,
+	"sh":      shellLanguage,
+	"shell":   shellLanguage,
+	"bash":    shellLanguage,
+	"zsh":     shellLanguage,
+	"console": shellLanguage,
+	"json":    jsonLanguage,
+	"yaml":    yamlLanguage,
+	"yml":     yamlLanguage,
+	"sql":     sqlLanguage,
+	"diff":    diffLanguage,
+	"patch":   diffLanguage,
+}
+
+// CodeBlock is a fenced code block with its options, the options are given in
+// the fence info string, for example ```{go linenos hl=2-4 title=main.go}
+type CodeBlock struct {
+	Language    string
+	Title       string
+	LineNumbers bool
+	Highlight   map[int]bool
+}
+
+// ParseCodeBlock parses the info string of a fenced code block
+func ParseCodeBlock(info string) *CodeBlock {
+	block := &CodeBlock{Highlight: make(map[int]bool)}
+
+	for _, field := range splitInfo(info) {
+		key, value := field, ""
+		if i := strings.Index(field, "="); i >= 0 {
+			key, value = field[:i], unquoteMeta(field[i+1:])
+		}
+
+		switch key {
+		case "linenos", "lineno", "numbers":
+			block.LineNumbers = true
+		case "hl", "hl_lines", "highlight":
+			block.Highlight = parseLineRanges(value)
+		case "title", "file", "filename":
+			block.Title = value
+		default:
+			if value == "" && block.Language == "" {
+				block.Language = strings.ToLower(strings.TrimPrefix(key, "."))
+			}
+		}
+	}
+
+	return block
+}
+
+// splitInfo splits an info string on spaces, keeping quoted values together
+func splitInfo(info string) []string {
+	var fields []string
+	var field []rune
+	var quote rune
+
+	for _, r := range info {
+		switch {
+		case quote != 0:
+			field = append(field, r)
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			field = append(field, r)
+			quote = r
+		case unicode.IsSpace(r):
+			if len(field) > 0 {
+				fields = append(fields, string(field))
+				field = field[:0]
+			}
+		default:
+			field = append(field, r)
+		}
+	}
+
+	if len(field) > 0 {
+		fields = append(fields, string(field))
+	}
+
+	return fields
+}
+
+// parseLineRanges parses line ranges such as 1,3-5
+func parseLineRanges(s string) map[int]bool {
+	lines := make(map[int]bool)
+
+	for _, part := range strings.Split(s, ",") {
+		bounds := strings.SplitN(strings.TrimSpace(part), "-", 2)
+
+		start, err := strconv.Atoi(bounds[0])
+		if err != nil {
+			continue
+		}
+
+		end := start
+		if len(bounds) == 2 {
+			if end, err = strconv.Atoi(bounds[1]); err != nil {
+				continue
+			}
+		}
+
+		for i := start; i <= end && i-start < 10000; i++ {
+			lines[i] = true
+		}
+	}
+
+	return lines
+}
+
+// Render writes the highlighted code block as HTML
+func (b *CodeBlock) Render(out *bytes.Buffer, code string) {
+	if b.Title != "" {
+		out.WriteString(`<figure class="code"><figcaption class="code__title">`)
+		out.WriteString(html.EscapeString(b.Title))
+		out.WriteString(`</figcaption>`)
+	}
+
+	// Line numbers are drawn with css counters so they are not part of the
+	// text when code is copied or summarized
+	if b.LineNumbers {
+		out.WriteString(`<pre class="highlight highlight--numbered">`)
+	} else {
+		out.WriteString(`<pre class="highlight">`)
+	}
+	if b.Language != "" {
+		out.WriteString(`<code class="language-`)
+		out.WriteString(html.EscapeString(b.Language))
+		out.WriteString(`">`)
+	} else {
+		out.WriteString(`<code>`)
+	}
+
+	lines := highlightLines(b.Language, strings.TrimSuffix(code, "\n"))
+	for i, line := range lines {
+		class := "line"
+		if b.Highlight[i+1] {
+			class += " line--highlight"
+		}
+
+		out.WriteString(`<span class="` + class + `">`)
+		for _, t := range line {
+			if t.class == "" {
+				out.WriteString(html.EscapeString(t.text))
+			} else {
+				out.WriteString(`<span class="hl-` + t.class + `">`)
+				out.WriteString(html.EscapeString(t.text))
+				out.WriteString(`</span>`)
+			}
+		}
+		out.WriteString("\n</span>")
+	}
+
+	out.WriteString("</code></pre>")
+
+	if b.Title != "" {
+		out.WriteString("</figure>")
+	}
+
+	out.WriteByte('\n')
+}
+
+// highlightLines tokenizes code and splits the tokens into lines
+func highlightLines(name string, code string) [][]token {
+	var tokens []token
+	if lang, ok := languages[name]; ok {
+		tokens = lang.tokenize(code)
+	} else {
+		tokens = []token{{text: code}}
+	}
+
+	lines := [][]token{nil}
+	for _, t := range tokens {
+		parts := strings.Split(t.text, "\n")
+		for i, part := range parts {
+			if i > 0 {
+				lines = append(lines, nil)
+			}
+			if part != "" {
+				lines[len(lines)-1] = append(lines[len(lines)-1], token{class: t.class, text: part})
+			}
+		}
+	}
+
+	return lines
+}
+
+func (l *language) tokenize(code string) []token {
+	if l.lines != nil {
+		var tokens []token
+		for i, line := range strings.Split(code, "\n") {
+			if i > 0 {
+				tokens = append(tokens, token{text: "\n"})
+			}
+			tokens = append(tokens, token{class: l.lines(line), text: line})
+		}
+		return tokens
+	}
+
+	var tokens []token
+	plain := 0
+	i := 0
+
+	emit := func(class string, end int) {
+		if plain < i {
+			tokens = append(tokens, token{text: code[plain:i]})
+		}
+		tokens = append(tokens, token{class: class, text: code[i:end]})
+		i, plain = end, end
+	}
+
+	for i < len(code) {
+		rest := code[i:]
+		c := code[i]
+
+		if l.lineComment(rest) {
+			end := strings.IndexByte(rest, '\n')
+			if end < 0 {
+				end = len(rest)
+			}
+			emit(hlComment, i+end)
+			continue
+		}
+
+		if open := l.blockComment[0]; open != "" && strings.HasPrefix(rest, open) {
+			end := strings.Index(rest[len(open):], l.blockComment[1])
+			if end < 0 {
+				end = len(rest)
+			} else {
+				end += len(open) + len(l.blockComment[1])
+			}
+			emit(hlComment, i+end)
+			continue
+		}
+
+		if strings.IndexByte(l.quotes, c) >= 0 || strings.IndexByte(l.rawQuotes, c) >= 0 {
+			end := scanString(rest, strings.IndexByte(l.rawQuotes, c) >= 0)
+			class := hlString
+			if l.keys && strings.HasPrefix(strings.TrimLeft(rest[end:], " \t"), ":") {
+				class = hlKey
+			}
+			emit(class, i+end)
+			continue
+		}
+
+		if l.variables && c == '$' {
+			end := 1
+			if strings.HasPrefix(rest, "${") {
+				if close := strings.IndexByte(rest, '}'); close > 0 {
+					end = close + 1
+				}
+			} else {
+				for end < len(rest) && isIdentByte(rest[end], "") {
+					end++
+				}
+			}
+			if end > 1 {
+				emit(hlVariable, i+end)
+				continue
+			}
+		}
+
+		if isDigit(c) && (i == 0 || !isIdentByte(code[i-1], l.identChars)) {
+			end := 1
+			for end < len(rest) && (isIdentByte(rest[end], "") || rest[end] == '.') {
+				end++
+			}
+			emit(hlNumber, i+end)
+			continue
+		}
+
+		if r, _ := utf8.DecodeRuneInString(rest); (unicode.IsLetter(r) || r == '_' || r == '~') && (i == 0 || !isIdentByte(code[i-1], l.identChars)) {
+			end := 0
+			for end < len(rest) {
+				r, size := utf8.DecodeRuneInString(rest[end:])
+				if !(unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '~' || strings.ContainsRune(l.identChars, r)) {
+					break
+				}
+				end += size
+			}
+
+			word := rest[:end]
+			if l.caseInsensitive {
+				word = strings.ToLower(word)
+			}
+
+			switch {
+			case l.keys && strings.HasPrefix(strings.TrimLeft(rest[end:], " \t"), ":"):
+				emit(hlKey, i+end)
+			case l.keywords[word]:
+				emit(hlKeyword, i+end)
+			case l.types[word]:
+				emit(hlType, i+end)
+			default:
+				i += end
+			}
+			continue
+		}
+
+		i++
+	}
+
+	if plain < len(code) {
+		tokens = append(tokens, token{text: code[plain:]})
+	}
+
+	return tokens
+}
+
+func (l *language) lineComment(s string) bool {
+	for _, prefix := range l.lineComments {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// scanString finds the end of a quoted string, raw strings have no escapes
+func scanString(s string, raw bool) int {
+	quote := s[0]
+	for i := 1; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && !raw:
+			i++
+		case s[i] == quote:
+			return i + 1
+		case s[i] == '\n' && !raw:
+			return i
+		}
+	}
+	return len(s)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentByte(c byte, extra string) bool {
+	return c == '_' || isDigit(c) || (c|0x20 >= 'a' && c|0x20 <= 'z') || c >= utf8.RuneSelf || strings.IndexByte(extra, c) >= 0
+}
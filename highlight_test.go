@@ -0,0 +1,192 @@
+package blog
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseCodeBlock(t *testing.T) {
+	tests := []struct {
+		info  string
+		block CodeBlock
+	}{
+		{"", CodeBlock{Highlight: map[int]bool{}}},
+		{"go", CodeBlock{Language: "go", Highlight: map[int]bool{}}},
+		{".Go linenos", CodeBlock{Language: "go", LineNumbers: true, Highlight: map[int]bool{}}},
+		{"go hl=2", CodeBlock{Language: "go", Highlight: map[int]bool{2: true}}},
+		{`sh title="run it.sh"`, CodeBlock{Language: "sh", Title: "run it.sh", Highlight: map[int]bool{}}},
+	}
+
+	for _, test := range tests {
+		if block := ParseCodeBlock(test.info); !reflect.DeepEqual(*block, test.block) {
+			t.Errorf("%q: expected %+v, got %+v", test.info, test.block, *block)
+		}
+	}
+}
+
+func TestParseLineRanges(t *testing.T) {
+	tests := []struct {
+		ranges string
+		lines  []int
+	}{
+		{"", nil},
+		{"3", []int{3}},
+		{"1,3-5", []int{1, 3, 4, 5}},
+		{" 2 , 4-4 ", []int{2, 4}},
+		{"5-3", nil},
+		{"a,2-b,-1,7", []int{7}},
+		{"1-100000", nil},
+	}
+
+	for _, test := range tests {
+		lines := parseLineRanges(test.ranges)
+
+		if test.ranges == "1-100000" {
+			if len(lines) != 10000 {
+				t.Errorf("%q: expected the range to be capped at 10000 lines, got %d", test.ranges, len(lines))
+			}
+			continue
+		}
+
+		if len(lines) != len(test.lines) {
+			t.Errorf("%q: expected %v, got %v", test.ranges, test.lines, lines)
+			continue
+		}
+		for _, line := range test.lines {
+			if !lines[line] {
+				t.Errorf("%q: expected line %d to be highlighted", test.ranges, line)
+			}
+		}
+	}
+}
+
+func TestScanString(t *testing.T) {
+	tests := []struct {
+		s   string
+		raw bool
+		end int
+	}{
+		{`"abc" rest`, false, 5},
+		{`"a\"b" rest`, false, 6},
+		{`"unterminated`, false, 13},
+		{`"ends at line"`[:5] + "\nnext", false, 5},
+		{`"trailing escape\`, false, 17},
+		{"`raw\\` rest", true, 6},
+		{"`multi\nline`", true, 12},
+		{"`unterminated\n", true, 14},
+	}
+
+	for _, test := range tests {
+		if end := scanString(test.s, test.raw); end != test.end {
+			t.Errorf("%q: expected end %d, got %d", test.s, test.end, end)
+		}
+	}
+}
+
+func TestHighlightLines(t *testing.T) {
+	tests := []struct {
+		name  string
+		lang  string
+		code  string
+		lines [][]token
+	}{
+		{
+			name:  "unknown language",
+			lang:  "cobol",
+			code:  "a\nb",
+			lines: [][]token{{{text: "a"}}, {{text: "b"}}},
+		},
+		{
+			name: "keywords and strings",
+			lang: "go",
+			code: `return "x"`,
+			lines: [][]token{{
+				{class: hlKeyword, text: "return"},
+				{text: " "},
+				{class: hlString, text: `"x"`},
+			}},
+		},
+		{
+			name: "unterminated string stops at the line end",
+			lang: "go",
+			code: "x := \"open\nreturn",
+			lines: [][]token{
+				{{text: "x := "}, {class: hlString, text: `"open`}},
+				{{class: hlKeyword, text: "return"}},
+			},
+		},
+		{
+			name: "unterminated block comment runs to the end",
+			lang: "go",
+			code: "/* open\nreturn",
+			lines: [][]token{
+				{{class: hlComment, text: "/* open"}},
+				{{class: hlComment, text: "return"}},
+			},
+		},
+		{
+			name: "line comment",
+			lang: "sh",
+			code: "echo $HOME # note",
+			lines: [][]token{{
+				{class: hlKeyword, text: "echo"},
+				{text: " "},
+				{class: hlVariable, text: "$HOME"},
+				{text: " "},
+				{class: hlComment, text: "# note"},
+			}},
+		},
+		{
+			name: "json keys",
+			lang: "json",
+			code: `{"a": 1}`,
+			lines: [][]token{{
+				{text: "{"},
+				{class: hlKey, text: `"a"`},
+				{text: ": "},
+				{class: hlNumber, text: "1"},
+				{text: "}"},
+			}},
+		},
+		{
+			name: "diff lines",
+			lang: "diff",
+			code: "+added\n-removed",
+			lines: [][]token{
+				{{class: hlInserted, text: "+added"}},
+				{{class: hlDeleted, text: "-removed"}},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		if lines := highlightLines(test.lang, test.code); !reflect.DeepEqual(lines, test.lines) {
+			t.Errorf("%s: expected %+v, got %+v", test.name, test.lines, lines)
+		}
+	}
+}
+
+func TestCodeBlockRender(t *testing.T) {
+	block := ParseCodeBlock("go hl=2 linenos title=<main.go>")
+
+	var out bytes.Buffer
+	block.Render(&out, "a\nb\n")
+
+	html := out.String()
+	for _, expected := range []string{
+		`<figcaption class="code__title">&lt;main.go&gt;</figcaption>`,
+		`<pre class="highlight highlight--numbered"><code class="language-go">`,
+		`<span class="line">a`,
+		`<span class="line line--highlight">b`,
+	} {
+		if !strings.Contains(html, expected) {
+			t.Errorf("expected %q in %q", expected, html)
+		}
+	}
+
+	if strings.Count(html, `class="line`) != 2 {
+		t.Errorf("expected the trailing newline not to add a line, got %q", html)
+	}
+}
@@ -0,0 +1,213 @@
+package blog
+
+import (
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gogits/git"
+)
+
+// Revision is a commit that changed an article
+type Revision struct {
+	ID      string
+	Parent  string
+	Author  *git.Signature
+	Date    time.Time
+	Message string
+	URL     *url.URL
+}
+
+// NewRevision creates a revision from a commit, the url links to the article
+// as rendered at that commit
+func NewRevision(commit *git.Commit, host string, article *Article) *Revision {
+	id := commit.Id.String()
+
+	parent := ""
+	if parentID, err := commit.ParentId(0); err == nil {
+		parent = parentID.String()
+	}
+
+	return &Revision{
+		ID:      id,
+		Parent:  parent,
+		Author:  commit.Author,
+		Date:    commitTime(commit),
+		Message: commit.Message(),
+		URL: &url.URL{
+			Host: host,
+			Path: "/commit/" + id + "/" + article.Link(),
+		},
+	}
+}
+
+// ShortID is the abbreviated commit id
+func (r *Revision) ShortID() string {
+	if len(r.ID) > 7 {
+		return r.ID[:7]
+	}
+	return r.ID
+}
+
+// Summary is the first line of the commit message
+func (r *Revision) Summary() string {
+	return strings.SplitN(r.Message, "\n", 2)[0]
+}
+
+// Histories maps the path of every file to the commits that changed it,
+// newest first
+type Histories map[string][]*git.Commit
+
+// FileHistories walks the commits reachable from commit once and lists the
+// commits that changed each file. A commit is compared with its parents by
+// descending only into trees that differ, a merge changes a file when it
+// differs from every parent. The files changed by a commit never change, so
+// they are read from and stored in changes when it is not nil
+func FileHistories(repo *git.Repository, commit *git.Commit, changes map[string][]string) (Histories, error) {
+	histories := make(Histories)
+
+	seen := make(map[string]bool)
+	queue := []*git.Commit{commit}
+
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		id := current.Id.String()
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		parents := make([]*git.Commit, current.ParentCount())
+		for i := range parents {
+			parent, err := current.Parent(i)
+			if err != nil {
+				return nil, err
+			}
+			parents[i] = parent
+		}
+
+		changed, ok := changes[id]
+		if !ok {
+			var err error
+			changed, err = changedFiles(repo, current, parents)
+			if err != nil {
+				return nil, err
+			}
+
+			if changes != nil {
+				changes[id] = changed
+			}
+		}
+
+		for _, name := range changed {
+			histories[name] = append(histories[name], current)
+		}
+
+		queue = append(queue, parents...)
+	}
+
+	for _, history := range histories {
+		sort.Sort(commitsByDate(history))
+	}
+
+	return histories, nil
+}
+
+// changedFiles lists the files added or modified by a commit
+func changedFiles(repo *git.Repository, commit *git.Commit, parents []*git.Commit) ([]string, error) {
+	tree := git.NewTree(repo, commit.TreeId())
+	counts := make(map[string]int)
+
+	if len(parents) == 0 {
+		if err := diffTrees(repo, nil, tree, "", counts); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, parent := range parents {
+		if err := diffTrees(repo, git.NewTree(repo, parent.TreeId()), tree, "", counts); err != nil {
+			return nil, err
+		}
+	}
+
+	var changed []string
+	for name, count := range counts {
+		if count == len(parents) || len(parents) == 0 {
+			changed = append(changed, name)
+		}
+	}
+
+	return changed, nil
+}
+
+// diffTrees counts the files in tree that are not in previous or differ from
+// it, previous is nil for a root commit
+func diffTrees(repo *git.Repository, previous *git.Tree, tree *git.Tree, dir string, counts map[string]int) error {
+	if previous != nil && previous.Id.Equal(tree.Id) {
+		return nil
+	}
+
+	old := make(map[string]*git.TreeEntry)
+	if previous != nil {
+		scanner, err := previous.Scanner()
+		if err != nil {
+			return err
+		}
+		for scanner.Scan() {
+			entry := scanner.TreeEntry()
+			old[entry.Name()] = entry
+		}
+		if err := scanner.Err(); err != nil {
+			return err
+		}
+	}
+
+	scanner, err := tree.Scanner()
+	if err != nil {
+		return err
+	}
+
+	for scanner.Scan() {
+		entry := scanner.TreeEntry()
+		name := path.Join(dir, entry.Name())
+
+		before, ok := old[entry.Name()]
+		if ok && before.Id.Equal(entry.Id) {
+			continue
+		}
+
+		if entry.IsDir() {
+			var sub *git.Tree
+			if ok && before.IsDir() {
+				sub = git.NewTree(repo, before.Id)
+			}
+
+			if err := diffTrees(repo, sub, git.NewTree(repo, entry.Id), name, counts); err != nil {
+				return err
+			}
+			continue
+		}
+
+		counts[name]++
+	}
+
+	return scanner.Err()
+}
+
+type commitsByDate []*git.Commit
+
+func (c commitsByDate) Len() int {
+	return len(c)
+}
+
+func (c commitsByDate) Less(i, j int) bool {
+	return commitTime(c[i]).After(commitTime(c[j]))
+}
+
+func (c commitsByDate) Swap(i, j int) {
+	c[i], c[j] = c[j], c[i]
+}
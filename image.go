@@ -0,0 +1,356 @@
+package blog
+
+import (
+	"bytes"
+	"container/list"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif" // register the gif decoder
+	"image/jpeg"
+	"image/png"
+	"math"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ImageWidths are the widths images can be resized to, other widths are
+// rejected so requests cannot fill the cache with arbitrary sizes
+var ImageWidths = []int{320, 640, 960, 1280, 1920}
+
+// imageTypes maps the extensions of images that can be resized to their
+// content type. GIFs are left out as they may be animated and resizing only
+// keeps the first frame
+var imageTypes = map[string]string{
+	".png":  "image/png",
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+}
+
+// maxCachedImages is the number of resized images kept in memory
+const maxCachedImages = 256
+
+// MaxImagePixels is the largest image, in pixels, that is decoded to be
+// resized. Larger images are only served at their original size
+var MaxImagePixels = 4096 * 4096
+
+// ResizedImage is an image encoded at a smaller width, ETag identifies the
+// source blob and width
+type ResizedImage struct {
+	Data        []byte
+	ContentType string
+	ETag        string
+}
+
+// imageCache holds resized images keyed by blob sha and width, the same blob
+// is shared between commits so images are not tied to a commit. The least
+// recently used image is evicted once the cache is full
+type imageCache struct {
+	lock   sync.Mutex
+	images map[string]*list.Element
+	order  *list.List
+}
+
+type cachedImage struct {
+	key   string
+	image *ResizedImage
+}
+
+func (c *imageCache) get(key string) (*ResizedImage, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	element, ok := c.images[key]
+	if !ok {
+		return nil, false
+	}
+
+	c.order.MoveToFront(element)
+	return element.Value.(*cachedImage).image, true
+}
+
+func (c *imageCache) set(key string, img *ResizedImage) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.images == nil {
+		c.images = make(map[string]*list.Element)
+		c.order = list.New()
+	}
+
+	if element, ok := c.images[key]; ok {
+		element.Value.(*cachedImage).image = img
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.images[key] = c.order.PushFront(&cachedImage{key: key, image: img})
+
+	for c.order.Len() > maxCachedImages {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.images, oldest.Value.(*cachedImage).key)
+	}
+}
+
+// IsImage checks if a file is an image that can be resized
+func IsImage(name string) bool {
+	_, ok := imageTypes[strings.ToLower(path.Ext(name))]
+	return ok
+}
+
+// validImageWidth checks the width is one of ImageWidths
+func validImageWidth(width int) bool {
+	for _, w := range ImageWidths {
+		if w == width {
+			return true
+		}
+	}
+	return false
+}
+
+// ResizeImage decodes a PNG or JPEG image and scales it down to width,
+// keeping the aspect ratio. Images that are already narrow enough are
+// returned as they are, images larger than MaxImagePixels are refused
+func ResizeImage(data []byte, width int) (*ResizedImage, error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	if format != "jpeg" && format != "png" {
+		return nil, fmt.Errorf("Unsupported image format %q", format)
+	}
+
+	if width >= config.Width {
+		return &ResizedImage{Data: data, ContentType: "image/" + format}, nil
+	}
+
+	if config.Width*config.Height > MaxImagePixels {
+		return nil, fmt.Errorf("Image of %dx%d pixels is too large to resize", config.Width, config.Height)
+	}
+
+	src, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	bounds := src.Bounds()
+
+	height := int(math.Floor(float64(bounds.Dy())*float64(width)/float64(bounds.Dx()) + 0.5))
+	if height < 1 {
+		height = 1
+	}
+
+	dst := resample(src, width, height)
+
+	var buffer bytes.Buffer
+	switch format {
+	case "jpeg":
+		err = jpeg.Encode(&buffer, dst, &jpeg.Options{Quality: 85})
+	default:
+		err = png.Encode(&buffer, dst)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &ResizedImage{Data: buffer.Bytes(), ContentType: "image/" + format}, nil
+}
+
+// imageSize decodes the dimensions of an image without decoding the pixels
+func imageSize(data []byte) (int, int, bool) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return 0, 0, false
+	}
+	return config.Width, config.Height, true
+}
+
+// imageSrcSet builds the srcset for an image in the tree, the srcset lists
+// every resized width smaller than the image followed by the original. GIFs
+// are not resized but still get their dimensions
+func (ctx *RenderContext) imageSrcSet(link string, src string) (srcset string, width int, height int, ok bool) {
+	u, err := url.Parse(link)
+	if err != nil {
+		return "", 0, 0, false
+	}
+
+	target, ok := ctx.linkTarget(u)
+	if !ok {
+		return "", 0, 0, false
+	}
+
+	resizable := IsImage(target)
+	if !resizable && strings.ToLower(path.Ext(target)) != ".gif" {
+		return "", 0, 0, false
+	}
+
+	data, err := readBlob(ctx.Tree, target)
+	if err != nil {
+		return "", 0, 0, false
+	}
+
+	width, height, ok = imageSize(data)
+	if !ok {
+		return "", 0, 0, false
+	}
+
+	// Images too large to resize are only offered at their original size
+	if !resizable || width*height > MaxImagePixels {
+		return "", width, height, true
+	}
+
+	var set []string
+	for _, w := range ImageWidths {
+		if w < width {
+			resized := baseURLPlaceholder + "_img/" + strconv.Itoa(w) + "/" + (&url.URL{Path: target}).EscapedPath()
+			set = append(set, resized+" "+strconv.Itoa(w)+"w")
+		}
+	}
+
+	if len(set) > 0 {
+		set = append(set, src+" "+strconv.Itoa(width)+"w")
+	}
+
+	return strings.Join(set, ", "), width, height, true
+}
+
+// catmullRom is the Catmull-Rom cubic filter, it has a support of 2
+func catmullRom(x float64) float64 {
+	x = math.Abs(x)
+	switch {
+	case x < 1:
+		return 1.5*x*x*x - 2.5*x*x + 1
+	case x < 2:
+		return -0.5*x*x*x + 2.5*x*x - 4*x + 2
+	}
+	return 0
+}
+
+// weights are the filter weights of the source pixels contributing to a
+// destination pixel
+type weights struct {
+	start  int
+	values []float64
+}
+
+// filterWeights calculates the weights for scaling a row of src pixels to dst
+// pixels, the filter is widened when scaling down so every source pixel
+// contributes
+func filterWeights(src, dst int) []weights {
+	scale := float64(src) / float64(dst)
+	support := 2.0
+	if scale > 1 {
+		support *= scale
+	}
+
+	result := make([]weights, dst)
+	for i := range result {
+		center := (float64(i)+0.5)*scale - 0.5
+		start := int(math.Ceil(center - support))
+		end := int(math.Floor(center + support))
+
+		w := weights{start: start}
+		sum := 0.0
+		for j := start; j <= end; j++ {
+			x := float64(j) - center
+			if scale > 1 {
+				x /= scale
+			}
+			v := catmullRom(x)
+			w.values = append(w.values, v)
+			sum += v
+		}
+
+		if sum != 0 {
+			for j := range w.values {
+				w.values[j] /= sum
+			}
+		}
+
+		result[i] = w
+	}
+
+	return result
+}
+
+// resample scales an image with a separable Catmull-Rom filter, colours are
+// filtered premultiplied by alpha so transparent pixels do not bleed
+func resample(src image.Image, width, height int) *image.RGBA {
+	bounds := src.Bounds()
+	sw, sh := bounds.Dx(), bounds.Dy()
+
+	in := image.NewRGBA(image.Rect(0, 0, sw, sh))
+	draw.Draw(in, in.Bounds(), src, bounds.Min, draw.Src)
+
+	clamp := func(i, n int) int {
+		if i < 0 {
+			return 0
+		}
+		if i >= n {
+			return n - 1
+		}
+		return i
+	}
+
+	// Horizontal pass into a float buffer of width x sh
+	tmp := make([]float64, width*sh*4)
+	for x, w := range filterWeights(sw, width) {
+		for y := 0; y < sh; y++ {
+			var r, g, b, a float64
+			for k, v := range w.values {
+				p := in.PixOffset(clamp(w.start+k, sw), y)
+				r += v * float64(in.Pix[p])
+				g += v * float64(in.Pix[p+1])
+				b += v * float64(in.Pix[p+2])
+				a += v * float64(in.Pix[p+3])
+			}
+			o := (y*width + x) * 4
+			tmp[o], tmp[o+1], tmp[o+2], tmp[o+3] = r, g, b, a
+		}
+	}
+
+	// Vertical pass into the destination
+	out := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y, w := range filterWeights(sh, height) {
+		for x := 0; x < width; x++ {
+			var c [4]float64
+			for k, v := range w.values {
+				o := (clamp(w.start+k, sh)*width + x) * 4
+				c[0] += v * tmp[o]
+				c[1] += v * tmp[o+1]
+				c[2] += v * tmp[o+2]
+				c[3] += v * tmp[o+3]
+			}
+
+			a := clampByte(c[3])
+			p := out.PixOffset(x, y)
+			for i := 0; i < 3; i++ {
+				// Premultiplied colour can not exceed alpha
+				v := clampByte(c[i])
+				if v > a {
+					v = a
+				}
+				out.Pix[p+i] = v
+			}
+			out.Pix[p+3] = a
+		}
+	}
+
+	return out
+}
+
+func clampByte(v float64) uint8 {
+	switch {
+	case v <= 0:
+		return 0
+	case v >= 255:
+		return 255
+	}
+	return uint8(v + 0.5)
+}
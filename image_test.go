@@ -0,0 +1,160 @@
+package blog
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func testImage(t *testing.T, format string, width, height int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{200, 100, 50, 255})
+		}
+	}
+
+	var buffer bytes.Buffer
+	var err error
+	switch format {
+	case "png":
+		err = png.Encode(&buffer, img)
+	case "jpeg":
+		err = jpeg.Encode(&buffer, img, nil)
+	case "gif":
+		err = gif.Encode(&buffer, img, nil)
+	}
+	if err != nil {
+		t.Fatalf("Could not encode %s: %v", format, err)
+	}
+
+	return buffer.Bytes()
+}
+
+func TestResizeImage(t *testing.T) {
+	tests := []struct {
+		name        string
+		format      string
+		width       int
+		height      int
+		resize      int
+		outWidth    int
+		outHeight   int
+		contentType string
+		err         string
+	}{
+		{"png", "png", 1000, 500, 320, 320, 160, "image/png", ""},
+		{"jpeg", "jpeg", 1000, 750, 640, 640, 480, "image/jpeg", ""},
+		{"rounded height", "png", 1000, 333, 320, 320, 107, "image/png", ""},
+		{"minimum height", "png", 1000, 1, 320, 320, 1, "image/png", ""},
+		{"narrow", "png", 300, 200, 320, 300, 200, "image/png", ""},
+		{"gif", "gif", 1000, 500, 320, 0, 0, "", `Unsupported image format "gif"`},
+		{"too large", "png", 2000, 1000, 320, 0, 0, "", "too large to resize"},
+	}
+
+	defer func(max int) { MaxImagePixels = max }(MaxImagePixels)
+	MaxImagePixels = 1000 * 1000
+
+	for _, test := range tests {
+		data := testImage(t, test.format, test.width, test.height)
+
+		img, err := ResizeImage(data, test.resize)
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("%s: expected error containing %q, got %v", test.name, test.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", test.name, err)
+			continue
+		}
+
+		if img.ContentType != test.contentType {
+			t.Errorf("%s: expected content type %s, got %s", test.name, test.contentType, img.ContentType)
+		}
+
+		config, format, err := image.DecodeConfig(bytes.NewReader(img.Data))
+		if err != nil {
+			t.Errorf("%s: could not decode the resized image: %v", test.name, err)
+			continue
+		}
+		if "image/"+format != test.contentType {
+			t.Errorf("%s: expected %s data, got %s", test.name, test.contentType, format)
+		}
+		if config.Width != test.outWidth || config.Height != test.outHeight {
+			t.Errorf("%s: expected %dx%d, got %dx%d", test.name, test.outWidth, test.outHeight, config.Width, config.Height)
+		}
+	}
+}
+
+func TestResample(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(10, 10, 110, 60))
+	for y := 10; y < 60; y++ {
+		for x := 10; x < 110; x++ {
+			if x < 60 {
+				src.Set(x, y, color.NRGBA{255, 0, 0, 255})
+			} else {
+				src.Set(x, y, color.NRGBA{0, 0, 255, 0})
+			}
+		}
+	}
+
+	out := resample(src, 20, 10)
+
+	if bounds := out.Bounds(); bounds != image.Rect(0, 0, 20, 10) {
+		t.Fatalf("expected bounds of 20x10 at the origin, got %v", bounds)
+	}
+
+	if c := out.RGBAAt(2, 5); c != (color.RGBA{255, 0, 0, 255}) {
+		t.Errorf("expected the opaque half to stay red, got %v", c)
+	}
+	if c := out.RGBAAt(17, 5); c != (color.RGBA{0, 0, 0, 0}) {
+		t.Errorf("expected the transparent half to stay transparent, got %v", c)
+	}
+
+	for x := 0; x < 20; x++ {
+		c := out.RGBAAt(x, 5)
+		if c.B > c.A || c.R > c.A {
+			t.Errorf("expected colour premultiplied by alpha at %d, got %v", x, c)
+		}
+	}
+}
+
+func TestImageCacheEviction(t *testing.T) {
+	var cache imageCache
+
+	for i := 0; i < maxCachedImages; i++ {
+		cache.set(strconv.Itoa(i), &ResizedImage{ETag: strconv.Itoa(i)})
+	}
+
+	// Using the oldest image makes the second oldest the next to be evicted
+	if _, ok := cache.get("0"); !ok {
+		t.Fatalf("expected image 0 to be cached")
+	}
+	cache.set(strconv.Itoa(maxCachedImages), &ResizedImage{})
+
+	if _, ok := cache.get("1"); ok {
+		t.Errorf("expected the least recently used image to be evicted")
+	}
+	for _, key := range []string{"0", "2", strconv.Itoa(maxCachedImages)} {
+		if _, ok := cache.get(key); !ok {
+			t.Errorf("expected image %s to still be cached", key)
+		}
+	}
+
+	// Replacing an image keeps the cache size
+	cache.set("2", &ResizedImage{ETag: "replaced"})
+	if img, _ := cache.get("2"); img.ETag != "replaced" {
+		t.Errorf("expected image 2 to be replaced, got %q", img.ETag)
+	}
+	if cache.order.Len() != maxCachedImages || len(cache.images) != maxCachedImages {
+		t.Errorf("expected %d cached images, got %d and %d", maxCachedImages, cache.order.Len(), len(cache.images))
+	}
+}
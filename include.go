@@ -0,0 +1,302 @@
+package blog
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	gotoken "go/token"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Sirupsen/logrus"
+)
+
+// codeLanguages maps file extensions to the language of included code
+var codeLanguages = map[string]string{
+	".go":    "go",
+	".sh":    "sh",
+	".bash":  "sh",
+	".json":  "json",
+	".yaml":  "yaml",
+	".yml":   "yaml",
+	".sql":   "sql",
+	".diff":  "diff",
+	".patch": "diff",
+}
+
+// expandCodeDirectives replaces .code lines in markdown with fenced code
+// blocks holding a file from the tree, in the spirit of the present tool
+//
+//	.code example.go                  the whole file
+//	.code example.go 10,20            lines 10 to 20, $ is the last line
+//	.code example.go /^func a/,/^}/   from a line matching the first regex to
+//	                                  the next line matching the second
+//	.code example.go main             a Go function, type or Type.Method
+//
+// A -numbers flag before the file adds line numbers. Paths are relative to
+// the article, directives inside fenced code blocks are left alone
+func expandCodeDirectives(ctx *RenderContext, source []byte) []byte {
+	if ctx.Tree == nil || !bytes.Contains(source, []byte(".code")) {
+		return source
+	}
+
+	var out bytes.Buffer
+	var fence string
+
+	scanner := bufio.NewScanner(bytes.NewReader(source))
+	scanner.Buffer(nil, len(source)+1)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case fence != "":
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+		case strings.HasPrefix(trimmed, "```"), strings.HasPrefix(trimmed, "~~~"):
+			fence = trimmed[:3]
+		case strings.HasPrefix(trimmed, ".code "):
+			block, err := codeBlock(ctx, strings.Fields(trimmed)[1:])
+			if err == nil {
+				out.WriteString(block)
+				continue
+			}
+
+			logrus.
+				WithError(err).
+				WithField("filename", ctx.Path).
+				WithField("directive", trimmed).
+				Warn("Code directive could not be expanded")
+
+			ctx.Diagnostics.Add(SeverityWarning, ctx.Path, "Code directive "+trimmed+" could not be expanded", err)
+		}
+
+		out.WriteString(line)
+		out.WriteByte('\n')
+	}
+
+	return out.Bytes()
+}
+
+// codeBlock builds the fenced code block for the arguments of a directive
+func codeBlock(ctx *RenderContext, args []string) (string, error) {
+	numbers := false
+	if len(args) > 0 && args[0] == "-numbers" {
+		numbers = true
+		args = args[1:]
+	}
+
+	if len(args) == 0 {
+		return "", fmt.Errorf("No file given")
+	}
+
+	name, ok := ctx.linkTarget(&url.URL{Path: args[0]})
+	if !ok {
+		return "", fmt.Errorf("File %s not found", args[0])
+	}
+
+	data, err := readBlob(ctx.Tree, name)
+	if err != nil {
+		return "", err
+	}
+
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	start, end := 1, len(lines)
+
+	if address := strings.Join(args[1:], " "); address != "" {
+		start, end, err = codeAddress(name, data, lines, address)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	code := strings.Join(lines[start-1:end], "\n")
+
+	// The fence has to be longer than any run of backticks in the code
+	fence := "```"
+	for strings.Contains(code, fence) {
+		fence += "`"
+	}
+
+	info := []string{codeLanguages[path.Ext(name)], "title=" + strconv.Quote(path.Base(name))}
+	if numbers {
+		info = append(info, "linenos")
+	}
+
+	return fmt.Sprintf("%s{%s}\n%s\n%s\n", fence, strings.TrimSpace(strings.Join(info, " ")), code, fence), nil
+}
+
+// codeAddress finds the lines selected by an address, lines are numbered
+// from 1 and the range is inclusive
+func codeAddress(name string, data []byte, lines []string, address string) (int, int, error) {
+	if strings.HasPrefix(address, "/") {
+		return regexpAddress(lines, address)
+	}
+
+	if address[0] >= '0' && address[0] <= '9' || address[0] == '$' {
+		return lineAddress(lines, address)
+	}
+
+	if path.Ext(name) != ".go" {
+		return 0, 0, fmt.Errorf("Symbols can only be included from Go files")
+	}
+
+	return symbolAddress(name, data, address)
+}
+
+func lineAddress(lines []string, address string) (int, int, error) {
+	bounds := strings.SplitN(address, ",", 2)
+
+	parse := func(s string) (int, error) {
+		s = strings.TrimSpace(s)
+		if s == "$" {
+			return len(lines), nil
+		}
+		return strconv.Atoi(s)
+	}
+
+	start, err := parse(bounds[0])
+	if err != nil {
+		return 0, 0, err
+	}
+
+	end := start
+	if len(bounds) == 2 {
+		if end, err = parse(bounds[1]); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	if start < 1 || end > len(lines) || start > end {
+		return 0, 0, fmt.Errorf("Lines %s out of range", address)
+	}
+
+	return start, end, nil
+}
+
+// regexpAddressPattern matches /start/ and /start/,/end/
+var regexpAddressPattern = regexp.MustCompile(`^/((?:\\/|[^/])*)/(?:,/((?:\\/|[^/])*)/)?$`)
+
+func regexpAddress(lines []string, address string) (int, int, error) {
+	match := regexpAddressPattern.FindStringSubmatch(address)
+	if match == nil {
+		return 0, 0, fmt.Errorf("Invalid address %s", address)
+	}
+
+	startRe, err := regexp.Compile(strings.Replace(match[1], `\/`, "/", -1))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	start := 0
+	for i, line := range lines {
+		if startRe.MatchString(line) {
+			start = i + 1
+			break
+		}
+	}
+
+	if start == 0 {
+		return 0, 0, fmt.Errorf("No line matches /%s/", match[1])
+	}
+
+	if match[2] == "" {
+		return start, start, nil
+	}
+
+	endRe, err := regexp.Compile(strings.Replace(match[2], `\/`, "/", -1))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	for i := start; i < len(lines); i++ {
+		if endRe.MatchString(lines[i]) {
+			return start, i + 1, nil
+		}
+	}
+
+	return 0, 0, fmt.Errorf("No line after %d matches /%s/", start, match[2])
+}
+
+// symbolAddress finds a function, method or type declaration in a Go file,
+// methods are given as Type.Method. The doc comment is included
+func symbolAddress(name string, data []byte, symbol string) (int, int, error) {
+	fset := gotoken.NewFileSet()
+	file, err := parser.ParseFile(fset, name, data, parser.ParseComments)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	recv, fn := "", symbol
+	if i := strings.Index(symbol, "."); i >= 0 {
+		recv, fn = symbol[:i], symbol[i+1:]
+	}
+
+	lines := func(start, end gotoken.Pos) (int, int, error) {
+		return fset.Position(start).Line, fset.Position(end).Line, nil
+	}
+
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			if decl.Name.Name != fn || receiverName(decl) != recv {
+				continue
+			}
+			if decl.Doc != nil {
+				return lines(decl.Doc.Pos(), decl.End())
+			}
+			return lines(decl.Pos(), decl.End())
+
+		case *ast.GenDecl:
+			if recv != "" || decl.Tok != gotoken.TYPE {
+				continue
+			}
+			for _, spec := range decl.Specs {
+				spec := spec.(*ast.TypeSpec)
+				if spec.Name.Name != fn {
+					continue
+				}
+
+				// A type on its own includes the type keyword and doc
+				if len(decl.Specs) == 1 {
+					if decl.Doc != nil {
+						return lines(decl.Doc.Pos(), decl.End())
+					}
+					return lines(decl.Pos(), decl.End())
+				}
+				if spec.Doc != nil {
+					return lines(spec.Doc.Pos(), spec.End())
+				}
+				return lines(spec.Pos(), spec.End())
+			}
+		}
+	}
+
+	return 0, 0, fmt.Errorf("Symbol %s not found in %s", symbol, name)
+}
+
+// receiverName gets the type name of a method receiver, or an empty string
+// for functions
+func receiverName(decl *ast.FuncDecl) string {
+	if decl.Recv == nil || len(decl.Recv.List) == 0 {
+		return ""
+	}
+
+	expr := decl.Recv.List[0].Type
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+
+	if ident, ok := expr.(*ast.Ident); ok {
+		return ident.Name
+	}
+
+	return ""
+}
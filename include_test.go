@@ -0,0 +1,84 @@
+package blog
+
+import (
+	"strings"
+	"testing"
+)
+
+const includeSource = `package main
+
+import "fmt"
+
+// Point is a point
+type Point struct {
+	X, Y int
+}
+
+// String formats the point
+func (p *Point) String() string {
+	return fmt.Sprint(p.X, p.Y)
+}
+
+type (
+	// A is grouped
+	A int
+	B int
+)
+
+func main() {
+	fmt.Println(Point{})
+}`
+
+func TestCodeAddress(t *testing.T) {
+	lines := strings.Split(includeSource, "\n")
+
+	tests := []struct {
+		name    string
+		address string
+		start   int
+		end     int
+		err     string
+	}{
+		{"main.go", "3", 3, 3, ""},
+		{"main.go", "5,8", 5, 8, ""},
+		{"main.go", "21,$", 21, 23, ""},
+		{"main.go", "$", 23, 23, ""},
+		{"main.go", "0", 0, 0, "out of range"},
+		{"main.go", "20,30", 0, 0, "out of range"},
+		{"main.go", "8,5", 0, 0, "out of range"},
+		{"main.go", "5,x", 0, 0, "invalid syntax"},
+		{"main.go", "/^func main/", 21, 21, ""},
+		{"main.go", "/^func main/,/^}/", 21, 23, ""},
+		{"main.go", `/import "fmt"/,/^type/`, 3, 6, ""},
+		{"main.go", "/^nothing/", 0, 0, "No line matches"},
+		{"main.go", "/^func main/,/^nothing/", 0, 0, "No line after 21"},
+		{"main.go", "/(/", 0, 0, "missing closing )"},
+		{"main.go", "/a/b/", 0, 0, "Invalid address"},
+		{"main.go", "main", 21, 23, ""},
+		{"main.go", "Point", 5, 8, ""},
+		{"main.go", "Point.String", 10, 13, ""},
+		{"main.go", "A", 16, 17, ""},
+		{"main.go", "B", 18, 18, ""},
+		{"main.go", "String", 0, 0, "Symbol String not found"},
+		{"main.sh", "main", 0, 0, "only be included from Go files"},
+	}
+
+	for _, test := range tests {
+		start, end, err := codeAddress(test.name, []byte(includeSource), lines, test.address)
+
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("%s %s: expected error containing %q, got %v", test.name, test.address, test.err, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s %s: unexpected error %v", test.name, test.address, err)
+			continue
+		}
+		if start != test.start || end != test.end {
+			t.Errorf("%s %s: expected lines %d-%d, got %d-%d", test.name, test.address, test.start, test.end, start, end)
+		}
+	}
+}
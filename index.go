@@ -1,5 +1,7 @@
 package blog
 
+import "time"
+
 // Index is an article index
 type Index []Article
 
@@ -8,22 +10,23 @@ func (i Index) Len() int {
 }
 
 func (i Index) Less(e, j int) bool {
-	return i[e].Mod.After(i[j].Mod)
+	return i[e].Date.After(i[j].Date)
 }
 
 func (i Index) Swap(e, j int) {
 	i[e], i[j] = i[j], i[e]
 }
 
-// Pages is the number of pages given a page length
+// Pages is the number of pages given a page length, the last page may be
+// partly filled
 func (i Index) Pages(length int) int {
-	return len(i) / length
+	return (len(i) + length - 1) / length
 }
 
 // Page gets the articles on a given page given a page length
 func (i Index) Page(page int, length int) []Article {
 	offset := page * length
-	if len(i) < offset {
+	if offset < 0 || len(i) < offset {
 		return nil
 	}
 
@@ -34,3 +37,14 @@ func (i Index) Page(page int, length int) []Article {
 
 	return p
 }
+
+// Published filters the index to articles published at the given time
+func (i Index) Published(now time.Time) Index {
+	published := make(Index, 0, len(i))
+	for _, article := range i {
+		if article.Published(now) {
+			published = append(published, article)
+		}
+	}
+	return published
+}
@@ -0,0 +1,47 @@
+package blog
+
+import "testing"
+
+func TestIndexPages(t *testing.T) {
+	tests := []struct {
+		articles int
+		length   int
+		pages    int
+	}{
+		{0, 20, 0},
+		{1, 20, 1},
+		{20, 20, 1},
+		{21, 20, 2},
+		{25, 20, 2},
+		{40, 20, 2},
+		{3, 1, 3},
+	}
+
+	for _, test := range tests {
+		if pages := make(Index, test.articles).Pages(test.length); pages != test.pages {
+			t.Errorf("%d articles of %d per page: expected %d pages, got %d", test.articles, test.length, test.pages, pages)
+		}
+	}
+}
+
+func TestIndexPage(t *testing.T) {
+	tests := []struct {
+		articles int
+		page     int
+		length   int
+		count    int
+	}{
+		{25, 0, 20, 20},
+		{25, 1, 20, 5},
+		{25, 2, 20, 0},
+		{20, 1, 20, 0},
+		{25, -1, 20, 0},
+		{0, 0, 20, 0},
+	}
+
+	for _, test := range tests {
+		if count := len(make(Index, test.articles).Page(test.page, test.length)); count != test.count {
+			t.Errorf("page %d of %d articles: expected %d articles, got %d", test.page, test.articles, test.count, count)
+		}
+	}
+}
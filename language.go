@@ -0,0 +1,118 @@
+package blog
+
+import (
+	"path"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// languagePattern matches the language codes a site may use, such as fr or
+// pt-BR, codes are used as path prefixes so they are kept short
+var languagePattern = regexp.MustCompile(`^[A-Za-z]{2,3}(-[A-Za-z0-9]{2,8})?$`)
+
+// Translation is a variant of an article in another language, Link is the
+// path of the variant under the base url
+type Translation struct {
+	Language string
+	Title    string
+	Link     string
+
+	article *Article
+}
+
+// Translations are the variants of an article in other languages
+type Translations []Translation
+
+// Published filters the translations to those whose article is published at
+// the given time
+func (t Translations) Published(now time.Time) Translations {
+	published := make(Translations, 0, len(t))
+	for _, translation := range t {
+		if translation.article == nil || translation.article.Published(now) {
+			published = append(published, translation)
+		}
+	}
+	return published
+}
+
+// HasLanguage checks if articles may be translated into a language, the
+// default language is not a translation
+func (s *Site) HasLanguage(lang string) bool {
+	if lang == s.Language {
+		return false
+	}
+
+	for _, l := range s.Languages {
+		if l == lang {
+			return true
+		}
+	}
+	return false
+}
+
+// AllLanguages lists the default language followed by the translations
+func (s *Site) AllLanguages() []string {
+	languages := []string{s.Language}
+	for _, lang := range s.Languages {
+		if lang != s.Language {
+			languages = append(languages, lang)
+		}
+	}
+	return languages
+}
+
+// splitLanguage splits the language suffix from a file name without its
+// extension, post.fr is post in French. Names without a suffix of one of the
+// languages of the site are in the default language
+func (s *Site) splitLanguage(name string) (string, string) {
+	ext := path.Ext(name)
+	lang := strings.TrimPrefix(ext, ".")
+
+	if lang == s.Language || s.HasLanguage(lang) {
+		return strings.TrimSuffix(name, ext), lang
+	}
+
+	return name, s.Language
+}
+
+// languagePrefix is the path translations in a language are served under,
+// the default language is served from the root of the site
+func (s *Site) languagePrefix(lang string) string {
+	if lang == "" || lang == s.Language {
+		return ""
+	}
+	return lang + "/"
+}
+
+// linkTranslations lists the other variants of each article on it, variants
+// are files that only differ by their language suffix
+func linkTranslations(site *Site, articles []*Article) {
+	groups := make(map[string][]*Article)
+	for _, article := range articles {
+		key, _ := site.splitLanguage(strings.TrimSuffix(article.Path, path.Ext(article.Path)))
+		groups[key] = append(groups[key], article)
+	}
+
+	for _, group := range groups {
+		if len(group) < 2 {
+			continue
+		}
+
+		for _, article := range group {
+			article.Translations = nil
+			for _, lang := range site.AllLanguages() {
+				for _, variant := range group {
+					if variant != article && variant.Language == lang {
+						article.Translations = append(article.Translations, Translation{
+							Language: variant.Language,
+							Title:    variant.Title,
+							Link:     variant.Link(),
+							article:  variant,
+						})
+					}
+				}
+			}
+		}
+	}
+}
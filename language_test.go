@@ -0,0 +1,108 @@
+package blog
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func testLanguageSite() *Site {
+	return &Site{Language: "en", Languages: []string{"fr", "pt-BR"}}
+}
+
+func TestSplitLanguage(t *testing.T) {
+	site := testLanguageSite()
+
+	tests := []struct {
+		name string
+		base string
+		lang string
+	}{
+		{"post", "post", "en"},
+		{"post.fr", "post", "fr"},
+		{"post.en", "post", "en"},
+		{"post.pt-BR", "post", "pt-BR"},
+		{"go/tips/post.fr", "go/tips/post", "fr"},
+		{"main.go", "main.go", "en"},
+		{"post.de", "post.de", "en"},
+		{"post.FR", "post.FR", "en"},
+		{"v1.2", "v1.2", "en"},
+	}
+
+	for _, test := range tests {
+		base, lang := site.splitLanguage(test.name)
+		if base != test.base || lang != test.lang {
+			t.Errorf("%q: expected (%q, %q), got (%q, %q)", test.name, test.base, test.lang, base, lang)
+		}
+	}
+}
+
+func TestLinkTranslations(t *testing.T) {
+	site := testLanguageSite()
+
+	article := func(path, name, lang, prefix string) *Article {
+		return &Article{Path: path, Name: name, Title: name + " " + lang, Language: lang, prefix: prefix}
+	}
+
+	post := article("post.md", "post", "en", "")
+	postFr := article("post.fr.md", "post", "fr", "fr/")
+	postPt := article("post.pt-BR.html", "post", "pt-BR", "pt-BR/")
+	tip := article("go/tip.md", "go/tip", "en", "")
+	tipFr := article("go/tip.fr.md", "go/tip", "fr", "fr/")
+	other := article("go/post.fr.md", "go/post", "fr", "fr/")
+	solo := article("solo.md", "solo", "en", "")
+
+	linkTranslations(site, []*Article{postPt, tipFr, postFr, solo, tip, post, other})
+
+	tests := []struct {
+		article      *Article
+		translations []string
+	}{
+		{post, []string{"fr fr/article/post/", "pt-BR pt-BR/article/post/"}},
+		{postFr, []string{"en article/post/", "pt-BR pt-BR/article/post/"}},
+		{postPt, []string{"en article/post/", "fr fr/article/post/"}},
+		{tip, []string{"fr fr/article/go/tip/"}},
+		{tipFr, []string{"en article/go/tip/"}},
+		{other, nil},
+		{solo, nil},
+	}
+
+	for _, test := range tests {
+		var translations []string
+		for _, translation := range test.article.Translations {
+			if translation.Title != translation.article.Title {
+				t.Errorf("%s: expected the title of %s, got %q", test.article.Path, translation.article.Path, translation.Title)
+			}
+			translations = append(translations, translation.Language+" "+translation.Link)
+		}
+
+		if !reflect.DeepEqual(translations, test.translations) {
+			t.Errorf("%s: expected translations %v, got %v", test.article.Path, test.translations, translations)
+		}
+	}
+}
+
+func TestTranslationsPublished(t *testing.T) {
+	now := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	translations := Translations{
+		{Language: "fr", article: &Article{Date: now}},
+		{Language: "de", article: &Article{Date: now, Draft: true}},
+		{Language: "es", article: &Article{Date: now.Add(time.Hour)}},
+		{Language: "it", article: &Article{Date: now.Add(-time.Hour)}},
+		{Language: "nl"},
+	}
+
+	var languages []string
+	for _, translation := range translations.Published(now) {
+		languages = append(languages, translation.Language)
+	}
+
+	if expected := []string{"fr", "it", "nl"}; !reflect.DeepEqual(languages, expected) {
+		t.Errorf("expected %v to be published, got %v", expected, languages)
+	}
+
+	if published := Translations(nil).Published(now); len(published) != 0 {
+		t.Errorf("expected no translations, got %v", published)
+	}
+}
@@ -0,0 +1,228 @@
+package blog
+
+import (
+	"bytes"
+	"net/url"
+	"path"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// BrokenLink is an internal link or image that does not point at an article
+// or a file in the tree, Kind is the element the link was found on
+type BrokenLink struct {
+	Source string `json:"source"`
+	Kind   string `json:"kind"`
+	Link   string `json:"link"`
+	Target string `json:"target"`
+	Reason string `json:"reason"`
+}
+
+// LinkReport lists the broken links found while building a commit
+type LinkReport []*BrokenLink
+
+// For gets the broken links found in a file
+func (r LinkReport) For(source string) LinkReport {
+	var report LinkReport
+	for _, link := range r {
+		if link.Source == source {
+			report = append(report, link)
+		}
+	}
+	return report
+}
+
+// Len implements sort.Interface.Len
+func (r LinkReport) Len() int {
+	return len(r)
+}
+
+// Less implements sort.Interface.Less
+func (r LinkReport) Less(i, j int) bool {
+	if r[i].Source != r[j].Source {
+		return r[i].Source < r[j].Source
+	}
+	return r[i].Link < r[j].Link
+}
+
+// Swap implements sort.Interface.Swap
+func (r LinkReport) Swap(i, j int) {
+	r[i], r[j] = r[j], r[i]
+}
+
+// siteRoutes are the first path segments of urls served by the blog rather
+// than the tree
+var siteRoutes = map[string]bool{
+	"":           true,
+	"page":       true,
+	"article":    true,
+	"section":    true,
+	"authors":    true,
+	"tags":       true,
+	"categories": true,
+	"branch":     true,
+	"commit":     true,
+	"blog.git":   true,
+	"_links":     true,
+	"_build":     true,
+	"_img":       true,
+}
+
+// linkAttributes are the elements that are checked and the attribute
+// holding their link
+var linkAttributes = map[string]string{
+	"a":      "href",
+	"link":   "href",
+	"img":    "src",
+	"script": "src",
+	"source": "src",
+}
+
+// linkRef is a link found in rendered HTML, Kind is the element it was found
+// on such as a or img
+type linkRef struct {
+	Kind string
+	Link string
+}
+
+// findLinks finds the links and images in HTML, attributes containing
+// template actions are skipped
+func findLinks(data []byte) []linkRef {
+	var refs []linkRef
+
+	tokenizer := html.NewTokenizer(bytes.NewReader(data))
+	for {
+		tt := tokenizer.Next()
+		if tt == html.ErrorToken {
+			return refs
+		}
+
+		if tt != html.StartTagToken && tt != html.SelfClosingTagToken {
+			continue
+		}
+
+		token := tokenizer.Token()
+
+		attr, ok := linkAttributes[token.Data]
+		if !ok {
+			continue
+		}
+
+		for _, a := range token.Attr {
+			if a.Key == attr && a.Val != "" && !strings.Contains(a.Val, "{{") {
+				refs = append(refs, linkRef{Kind: token.Data, Link: a.Val})
+			}
+		}
+	}
+}
+
+// linkChecker checks links against the files, articles, translations and
+// pages of a built commit
+type linkChecker struct {
+	files        fileTree
+	articles     map[string]*Article
+	translations map[string]map[string]*Article
+	pages        map[string]*Article
+}
+
+// newLinkChecker creates a link checker for a built commit
+func newLinkChecker(n node) *linkChecker {
+	return &linkChecker{
+		files:        n.files(),
+		articles:     n.Articles,
+		translations: n.Translations,
+		pages:        n.Pages,
+	}
+}
+
+// check checks the internal links in a file. Relative links are only checked
+// for articles, links in templates are relative to the page they are shown on
+func (l *linkChecker) check(source string, data []byte, relative bool) LinkReport {
+	var report LinkReport
+
+	for _, ref := range findLinks(data) {
+		target, reason, ok := l.checkLink(source, ref.Link, relative)
+		if ok {
+			continue
+		}
+
+		report = append(report, &BrokenLink{
+			Source: source,
+			Kind:   ref.Kind,
+			Link:   strings.Replace(ref.Link, baseURLPlaceholder, "/", 1),
+			Target: target,
+			Reason: reason,
+		})
+	}
+
+	return report
+}
+
+// checkLink checks a single link, the target and reason describe why a link
+// is broken
+func (l *linkChecker) checkLink(source string, link string, relative bool) (target string, reason string, ok bool) {
+	u, err := url.Parse(link)
+	if err != nil {
+		return link, "invalid url", false
+	}
+
+	if u.Scheme != "" || u.Host != "" || u.Path == "" {
+		return "", "", true
+	}
+
+	p := u.Path
+	switch {
+	case strings.HasPrefix(p, baseURLPlaceholder):
+		p = strings.TrimPrefix(p, baseURLPlaceholder)
+	case strings.HasPrefix(p, "/"):
+		p = strings.TrimPrefix(p, "/")
+	case !relative:
+		return "", "", true
+	default:
+		p = path.Join(path.Dir(source), p)
+		if p == ".." || strings.HasPrefix(p, "../") {
+			return p, "outside of the repository", false
+		}
+	}
+
+	// Translations are served under their language, such as fr/article/name,
+	// the index of the language is fr/ and fr/page/1
+	articles := l.articles
+	if parts := strings.SplitN(p, "/", 2); len(parts) == 1 || !strings.HasPrefix(parts[1], "article/") {
+		if _, ok := l.translations[parts[0]]; ok && (len(parts) == 1 || parts[1] == "" || strings.HasPrefix(parts[1], "page/")) {
+			return "", "", true
+		}
+	} else if translated, ok := l.translations[parts[0]]; ok {
+		articles, p = translated, parts[1]
+	}
+
+	// Article urls may continue past the name, such as article/name/history
+	if name := strings.TrimPrefix(p, "article/"); name != p {
+		name = strings.Trim(name, "/")
+		for n := name; n != "." && n != ""; n = path.Dir(n) {
+			if _, ok := articles[n]; ok {
+				return "", "", true
+			}
+		}
+		return name, "missing article", false
+	}
+
+	if _, err := l.files.GetBlobByPath(p); err == nil {
+		return "", "", true
+	}
+
+	if _, ok := l.pages[strings.Trim(p, "/")]; ok {
+		return "", "", true
+	}
+
+	if route := strings.SplitN(p, "/", 2)[0]; strings.HasPrefix(u.Path, "/") && siteRoutes[route] {
+		return "", "", true
+	}
+
+	if articleExtensions[path.Ext(p)] {
+		return p, "missing article", false
+	}
+
+	return p, "missing file", false
+}
@@ -0,0 +1,73 @@
+package blog
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLinkCheckerCheck(t *testing.T) {
+	links := &linkChecker{
+		files: testTree{
+			"go/images/gopher.png": strings.Repeat("1", 40),
+			"style.css":            strings.Repeat("2", 40),
+		},
+		articles: map[string]*Article{
+			"hello":   {Name: "hello"},
+			"go/post": {Name: "go/post"},
+		},
+		translations: map[string]map[string]*Article{
+			"fr": {"bonjour": {Name: "bonjour"}},
+		},
+		pages: map[string]*Article{
+			"about": {Name: "about"},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		link     string
+		relative bool
+		target   string
+		reason   string
+	}{
+		{"relative file", `<img src="images/gopher.png">`, true, "", ""},
+		{"missing file", `<img src="images/missing.png">`, true, "go/images/missing.png", "missing file"},
+		{"rooted file", `<link href="/style.css">`, true, "", ""},
+		{"outside of the repository", `<a href="../../x.png">`, true, "../x.png", "outside of the repository"},
+		{"article", `<a href="/~base~/article/hello/">`, true, "", ""},
+		{"article history", `<a href="/~base~/article/go/post/history/">`, true, "", ""},
+		{"missing article", `<a href="/~base~/article/nope/">`, true, "nope", "missing article"},
+		{"missing markdown source", `<a href="nope.md">`, true, "go/nope.md", "missing article"},
+		{"page", `<a href="/~base~/about/">`, true, "", ""},
+		{"site route", `<a href="/~base~/tags/go/">`, true, "", ""},
+		{"language index", `<a href="/~base~/fr/">`, true, "", ""},
+		{"language page", `<a href="/~base~/fr/page/1/">`, true, "", ""},
+		{"translated article", `<a href="/~base~/fr/article/bonjour/">`, true, "", ""},
+		{"untranslated article", `<a href="/~base~/fr/article/hello/">`, true, "hello", "missing article"},
+		{"unknown language", `<a href="/~base~/de/">`, true, "de/", "missing file"},
+		{"external", `<a href="https://example.com/missing">`, true, "", ""},
+		{"scheme", `<a href="mailto:me@example.com">`, true, "", ""},
+		{"fragment", `<a href="#top">`, true, "", ""},
+		{"relative in a template", `<img src="missing.png">`, false, "", ""},
+		{"template action", `<a href="{{.Link}}">`, true, "", ""},
+	}
+
+	for _, test := range tests {
+		report := links.check("go/post.md", []byte(test.link), test.relative)
+
+		if test.reason == "" {
+			if len(report) != 0 {
+				t.Errorf("%s: expected no broken links, got %+v", test.name, *report[0])
+			}
+			continue
+		}
+
+		if len(report) != 1 {
+			t.Errorf("%s: expected a broken link, got %d", test.name, len(report))
+			continue
+		}
+		if link := report[0]; link.Target != test.target || link.Reason != test.reason || link.Source != "go/post.md" {
+			t.Errorf("%s: expected %q (%s) in go/post.md, got %q (%s) in %s", test.name, test.target, test.reason, link.Target, link.Reason, link.Source)
+		}
+	}
+}
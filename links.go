@@ -0,0 +1,115 @@
+package blog
+
+import (
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/gogits/git"
+)
+
+// baseURLPlaceholder prefixes links rewritten during rendering, it is
+// replaced with the base url of the request when the article is served so
+// the same cached HTML works under /branch/:branch/ and /commit/:commit/
+const baseURLPlaceholder = "/~base~/"
+
+// articleExtensions are the extensions of files that links are rewritten to
+// article urls for
+var articleExtensions = map[string]bool{
+	".md":       true,
+	".markdown": true,
+}
+
+// ResolveLink rewrites a link relative to the file being rendered into a site
+// url. Links to articles become article urls and links to other files in the
+// tree become file urls, anything else is returned untouched with ok false
+func (ctx *RenderContext) ResolveLink(link string) (string, bool) {
+	u, err := url.Parse(link)
+	if err != nil {
+		return link, false
+	}
+
+	target, ok := ctx.linkTarget(u)
+	if !ok {
+		return link, false
+	}
+
+	resolved := baseURLPlaceholder + (&url.URL{Path: target}).EscapedPath()
+	if ext := path.Ext(target); articleExtensions[ext] {
+		name, lang := articleName(ctx.Tree, ctx.Site, target)
+		if page, ok := pageName(name); ok {
+			resolved = baseURLPlaceholder + (&url.URL{Path: page}).EscapedPath() + "/"
+		} else {
+			link := ctx.Site.languagePrefix(lang) + "article/" + name
+			resolved = baseURLPlaceholder + (&url.URL{Path: link}).EscapedPath() + "/"
+		}
+	}
+
+	if u.RawQuery != "" {
+		resolved += "?" + u.RawQuery
+	}
+	if u.Fragment != "" {
+		resolved += "#" + u.Fragment
+	}
+
+	return resolved, true
+}
+
+// linkTarget gets the path of the blob in the tree a link points at
+func (ctx *RenderContext) linkTarget(u *url.URL) (string, bool) {
+	if ctx.Tree == nil || u.Scheme != "" || u.Host != "" || u.Path == "" {
+		return "", false
+	}
+
+	target := u.Path
+	if !strings.HasPrefix(target, "/") {
+		target = path.Join(path.Dir(ctx.Path), target)
+	}
+	target = strings.TrimPrefix(path.Clean(target), "/")
+
+	if target == "" || strings.HasPrefix(target, "../") || target == ".." {
+		return "", false
+	}
+
+	if _, err := ctx.Tree.GetBlobByPath(target); err != nil {
+		return "", false
+	}
+
+	return target, true
+}
+
+// articleName gets the name and language an article file is served under,
+// taking a slug set in its front matter into account
+func articleName(tree *git.Tree, site *Site, file string) (string, string) {
+	name, lang := site.splitLanguage(strings.TrimSuffix(file, path.Ext(file)))
+
+	data, err := readBlob(tree, file)
+	if err != nil {
+		return name, lang
+	}
+
+	meta, _, err := SplitFrontMatter(data)
+	if err != nil {
+		return name, lang
+	}
+
+	if slug := meta.String("slug"); slug != "" {
+		dir := path.Dir(file)
+		if dir == "." {
+			dir = ""
+		}
+		return path.Join(dir, slug), lang
+	}
+
+	return name, lang
+}
+
+// expandBaseURL replaces the placeholder in rewritten links with the base url
+func expandBaseURL(data []byte, baseURL *url.URL) string {
+	base := "/"
+	if baseURL != nil {
+		base = baseURL.String()
+	}
+
+	return strings.Replace(string(data), baseURLPlaceholder, base, -1)
+}
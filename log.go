@@ -24,6 +24,20 @@ func StoreLog(ctx context.Context, log logrus.FieldLogger) context.Context {
 	return context.WithValue(ctx, "logger", log)
 }
 
+// GetRequestID gets the request id stored by the metrics middleware, it is
+// generated from the request if none is stored
+func GetRequestID(ctx context.Context, r *http.Request) string {
+	if id, ok := ctx.Value("request_id").(string); ok {
+		return id
+	}
+	return RequestID(r)
+}
+
+// StoreRequestID stores a request id in the context
+func StoreRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, "request_id", id)
+}
+
 // RequestID generates a request id from a request
 func RequestID(r *http.Request) string {
 	ptr := uint64(uintptr(unsafe.Pointer(r)))
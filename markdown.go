@@ -0,0 +1,236 @@
+package blog
+
+import (
+	"bytes"
+	"html"
+	"strconv"
+
+	"github.com/russross/blackfriday"
+)
+
+// MarkdownOptions are the markdown settings from the [markdown] section of
+// the site config, the defaults match blackfriday.MarkdownCommon
+type MarkdownOptions struct {
+	Footnotes       bool
+	DefinitionLists bool
+	HardLineBreaks  bool
+	Smartypants     bool
+	SmartFractions  bool
+	LatexDashes     bool
+	RawHTML         bool
+}
+
+// DefaultMarkdownOptions are used when the site config does not set an option
+var DefaultMarkdownOptions = MarkdownOptions{
+	DefinitionLists: true,
+	Smartypants:     true,
+	SmartFractions:  true,
+	LatexDashes:     true,
+	RawHTML:         true,
+}
+
+// HTMLFlags gets the blackfriday html renderer flags for the options
+func (o MarkdownOptions) HTMLFlags() int {
+	flags := blackfriday.HTML_USE_XHTML
+
+	if o.Smartypants {
+		flags |= blackfriday.HTML_USE_SMARTYPANTS | blackfriday.HTML_SMARTYPANTS_DASHES
+	}
+	if o.SmartFractions {
+		flags |= blackfriday.HTML_SMARTYPANTS_FRACTIONS
+	}
+	if o.LatexDashes {
+		flags |= blackfriday.HTML_SMARTYPANTS_LATEX_DASHES
+	}
+	if !o.RawHTML {
+		flags |= blackfriday.HTML_SKIP_HTML
+	}
+
+	return flags
+}
+
+// Extensions gets the blackfriday extension flags for the options
+func (o MarkdownOptions) Extensions() int {
+	extensions := blackfriday.EXTENSION_NO_INTRA_EMPHASIS |
+		blackfriday.EXTENSION_TABLES |
+		blackfriday.EXTENSION_FENCED_CODE |
+		blackfriday.EXTENSION_AUTOLINK |
+		blackfriday.EXTENSION_STRIKETHROUGH |
+		blackfriday.EXTENSION_SPACE_HEADERS |
+		blackfriday.EXTENSION_HEADER_IDS |
+		blackfriday.EXTENSION_BACKSLASH_LINE_BREAK
+
+	if o.Footnotes {
+		extensions |= blackfriday.EXTENSION_FOOTNOTES
+	}
+	if o.DefinitionLists {
+		extensions |= blackfriday.EXTENSION_DEFINITION_LISTS
+	}
+	if o.HardLineBreaks {
+		extensions |= blackfriday.EXTENSION_HARD_LINE_BREAK
+	}
+
+	return extensions
+}
+
+func (o *MarkdownOptions) apply(meta FrontMatter, prefix string) {
+	setBool(meta, prefix+"footnotes", &o.Footnotes)
+	setBool(meta, prefix+"definition_lists", &o.DefinitionLists)
+	setBool(meta, prefix+"hard_line_breaks", &o.HardLineBreaks)
+	setBool(meta, prefix+"smartypants", &o.Smartypants)
+	setBool(meta, prefix+"smart_fractions", &o.SmartFractions)
+	setBool(meta, prefix+"latex_dashes", &o.LatexDashes)
+	setBool(meta, prefix+"raw_html", &o.RawHTML)
+}
+
+// markdownRenderer wraps the blackfriday HTML renderer, code blocks are
+// highlighted when the article is built rather than in the browser
+type markdownRenderer struct {
+	blackfriday.Renderer
+	ctx *RenderContext
+	ids map[string]bool
+}
+
+func newMarkdownRenderer(ctx *RenderContext, options MarkdownOptions) *markdownRenderer {
+	return &markdownRenderer{
+		Renderer: blackfriday.HtmlRenderer(options.HTMLFlags(), "", ""),
+		ctx:      ctx,
+		ids:      make(map[string]bool),
+	}
+}
+
+// BlockCode implements blackfriday.Renderer.BlockCode
+func (r *markdownRenderer) BlockCode(out *bytes.Buffer, text []byte, info string) {
+	if out.Len() > 0 {
+		out.WriteByte('\n')
+	}
+	ParseCodeBlock(info).Render(out, string(text))
+}
+
+// BlockHtml implements blackfriday.Renderer.BlockHtml, the <!--more-->
+// marker is kept even when raw HTML is disabled
+func (r *markdownRenderer) BlockHtml(out *bytes.Buffer, text []byte) {
+	if string(bytes.TrimSpace(text)) != MoreMarker {
+		r.Renderer.BlockHtml(out, text)
+		return
+	}
+
+	if out.Len() > 0 {
+		out.WriteByte('\n')
+	}
+	out.WriteString(MoreMarker + "\n")
+}
+
+// Link implements blackfriday.Renderer.Link, links to files in the tree are
+// rewritten to site urls
+func (r *markdownRenderer) Link(out *bytes.Buffer, link []byte, title []byte, content []byte) {
+	resolved, _ := r.ctx.ResolveLink(string(link))
+	r.Renderer.Link(out, []byte(resolved), title, content)
+}
+
+// Image implements blackfriday.Renderer.Image, images in the tree are
+// rewritten to site urls and given a srcset of resized images. Every image is
+// lazily loaded
+func (r *markdownRenderer) Image(out *bytes.Buffer, link []byte, title []byte, alt []byte) {
+	src, _ := r.ctx.ResolveLink(string(link))
+
+	out.WriteString(`<img src="` + html.EscapeString(src) + `"`)
+
+	if srcset, width, height, ok := r.ctx.imageSrcSet(string(link), src); ok {
+		if srcset != "" {
+			out.WriteString(` srcset="` + html.EscapeString(srcset) + `"`)
+			out.WriteString(` sizes="(max-width: ` + strconv.Itoa(width) + `px) 100vw, ` + strconv.Itoa(width) + `px"`)
+		}
+		out.WriteString(` width="` + strconv.Itoa(width) + `" height="` + strconv.Itoa(height) + `"`)
+	}
+
+	out.WriteString(` alt="` + html.EscapeString(string(alt)) + `"`)
+	if len(title) > 0 {
+		out.WriteString(` title="` + html.EscapeString(string(title)) + `"`)
+	}
+
+	out.WriteString(` loading="lazy" />`)
+}
+
+// Header implements blackfriday.Renderer.Header, every heading is given an
+// anchor derived from its text unless one is set with {#id}, duplicates are
+// suffixed with a counter so anchors are unique and stable between builds
+func (r *markdownRenderer) Header(out *bytes.Buffer, text func() bool, level int, id string) {
+	marker := out.Len()
+	if marker > 0 {
+		out.WriteByte('\n')
+	}
+
+	start := out.Len()
+	if !text() {
+		out.Truncate(marker)
+		return
+	}
+
+	content := string(out.Bytes()[start:])
+	out.Truncate(start)
+
+	title := stripTags(content)
+	if id == "" {
+		id = Slugify(title)
+	}
+	id = r.uniqueID(id)
+
+	tag := "h" + strconv.Itoa(level)
+	out.WriteString("<" + tag + ` id="` + html.EscapeString(id) + `">`)
+	out.WriteString(content)
+	out.WriteString("</" + tag + ">\n")
+
+	r.ctx.Headings = append(r.ctx.Headings, &TOCEntry{
+		Level: level,
+		ID:    id,
+		Title: title,
+	})
+}
+
+func (r *markdownRenderer) uniqueID(id string) string {
+	if id == "" {
+		id = "section"
+	}
+
+	unique := id
+	for i := 1; r.ids[unique]; i++ {
+		unique = id + "-" + strconv.Itoa(i)
+	}
+
+	r.ids[unique] = true
+	return unique
+}
+
+// stripTags reduces rendered HTML to its text content
+func stripTags(s string) string {
+	var text bytes.Buffer
+	tag := false
+
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '<':
+			tag = true
+		case s[i] == '>' && tag:
+			tag = false
+		case !tag:
+			text.WriteByte(s[i])
+		}
+	}
+
+	return html.UnescapeString(text.String())
+}
+
+func renderMarkdown(ctx *RenderContext, source []byte) ([]byte, error) {
+	options := DefaultMarkdownOptions
+	if ctx.Site != nil {
+		options = ctx.Site.Markdown
+	}
+
+	source = expandCodeDirectives(ctx, source)
+
+	renderer := newMarkdownRenderer(ctx, options)
+	return blackfriday.MarkdownOptions(source, renderer, blackfriday.Options{
+		Extensions: options.Extensions(),
+	}), nil
+}
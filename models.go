@@ -13,6 +13,12 @@ type IndexModel struct {
 	Count    int
 	Articles []Article
 	BaseURL  *url.URL
+	Path     string
+	Site     *Site
+	Language string
+
+	BrokenLinks LinkReport
+	Diagnostics Diagnostics
 }
 
 // Pagination creates pagination for the index
@@ -22,7 +28,7 @@ func (i *IndexModel) Pagination() template.HTML {
 		if e < 0 || e >= i.Count {
 			continue
 		}
-		page := fmt.Sprintf(`pages/%d`, e)
+		page := fmt.Sprintf(`%spage/%d`, i.Path, e)
 		url, _ := i.BaseURL.Parse(page)
 		classes := "pagination__page"
 		if e == i.Page {
@@ -32,12 +38,12 @@ func (i *IndexModel) Pagination() template.HTML {
 	}
 
 	if i.Page-3 > 0 {
-		url, _ := i.BaseURL.Parse("page/0")
+		url, _ := i.BaseURL.Parse(i.Path + "page/0")
 		p = fmt.Sprintf(`<a href="%s">%d</a> ...`, url, 0) + p
 	}
 
 	if i.Page+2 < i.Count {
-		page := fmt.Sprintf(`pages/%d`, i.Count-1)
+		page := fmt.Sprintf(`%spage/%d`, i.Path, i.Count-1)
 		url, _ := i.BaseURL.Parse(page)
 		p += fmt.Sprintf(`... <a href="%s">%d</a>`, url, i.Count-1)
 	}
@@ -46,9 +52,110 @@ func (i *IndexModel) Pagination() template.HTML {
 	return template.HTML(pagination)
 }
 
+// SectionModel is the model passed to the section template
+type SectionModel struct {
+	*IndexModel
+	Section string
+}
+
+// TagsModel is the model passed to the tags template, it is also used for
+// categories
+type TagsModel struct {
+	GitURL   string
+	Taxonomy string
+	Tags     []*Term
+	BaseURL  *url.URL
+	Site     *Site
+}
+
+// TagModel is the model passed to the tag template, it is also used for a
+// single category
+type TagModel struct {
+	*IndexModel
+	Taxonomy string
+	Tag      *Term
+}
+
+// AuthorModel is the model passed to the author template
+type AuthorModel struct {
+	*IndexModel
+	Author *AuthorPage
+}
+
+// HistoryModel is the model passed to the history template
+type HistoryModel struct {
+	*ArticleModel
+	Page      int
+	Count     int
+	Revisions []*Revision
+}
+
+// Pagination creates pagination for the history
+func (h *HistoryModel) Pagination() template.HTML {
+	index := &IndexModel{
+		Page:    h.Page,
+		Count:   h.Count,
+		BaseURL: h.BaseURL,
+		Path:    h.Article.Link() + "history/",
+	}
+	return index.Pagination()
+}
+
+// DiffModel is the model passed to the diff template
+type DiffModel struct {
+	*ArticleModel
+	From  *Revision
+	To    *Revision
+	Split bool
+	Lines []DiffLine
+	Rows  []DiffRow
+}
+
 // ArticleModel is the model passed to the article template
 type ArticleModel struct {
 	GitURL  string
 	Article *Article
 	BaseURL *url.URL
+	Site    *Site
+
+	// Translations are the other variants of the article, Alternates are
+	// every variant including the article, for hreflang links. Links are
+	// absolute
+	Translations []Translation
+	Alternates   []Translation
+
+	BrokenLinks LinkReport
+	Diagnostics Diagnostics
+}
+
+// PageModel is the model passed to the page template
+type PageModel struct {
+	GitURL  string
+	Page    *Article
+	BaseURL *url.URL
+	Site    *Site
+
+	BrokenLinks LinkReport
+	Diagnostics Diagnostics
+}
+
+// ErrorModel is the model passed to the 404 and error templates
+type ErrorModel struct {
+	GitURL     string
+	BaseURL    *url.URL
+	Site       *Site
+	Status     int
+	StatusText string
+	Message    string
+	RequestID  string
+}
+
+// BuildModel is the model passed to the build template
+type BuildModel struct {
+	GitURL      string
+	BaseURL     *url.URL
+	Site        *Site
+	Commit      string
+	Diagnostics Diagnostics
+	BrokenLinks LinkReport
 }
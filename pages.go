@@ -0,0 +1,50 @@
+package blog
+
+import (
+	"strings"
+	"time"
+)
+
+// PagesDir is the directory of the tree standalone pages are read from, pages
+// such as pages/about.md are served at /about/ and are not dated or listed
+// with the articles
+const PagesDir = "pages"
+
+// Pages is the navigation list of standalone pages, ordered by the weight set
+// in their front matter and then by title
+type Pages []*Article
+
+func (p Pages) Len() int {
+	return len(p)
+}
+
+func (p Pages) Less(i, j int) bool {
+	if p[i].Weight != p[j].Weight {
+		return p[i].Weight < p[j].Weight
+	}
+	return p[i].Title < p[j].Title
+}
+
+func (p Pages) Swap(i, j int) {
+	p[i], p[j] = p[j], p[i]
+}
+
+// Published filters the pages to those published at the given time
+func (p Pages) Published(now time.Time) Pages {
+	published := make(Pages, 0, len(p))
+	for _, page := range p {
+		if page.Published(now) {
+			published = append(published, page)
+		}
+	}
+	return published
+}
+
+// pageName gets the name a page is served under from its article name, ok is
+// false for files outside of the pages directory
+func pageName(name string) (string, bool) {
+	if !strings.HasPrefix(name, PagesDir+"/") {
+		return "", false
+	}
+	return strings.TrimPrefix(name, PagesDir+"/"), true
+}
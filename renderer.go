@@ -0,0 +1,99 @@
+package blog
+
+import (
+	"bytes"
+	"html"
+	"strings"
+	"sync"
+
+	"github.com/gogits/git"
+)
+
+// RenderContext describes the file being rendered and the site config of the
+// commit, renderers record the headings they render in Headings and any
+// problems they find in Diagnostics
+type RenderContext struct {
+	Tree        *git.Tree
+	Path        string
+	Site        *Site
+	Headings    []*TOCEntry
+	Diagnostics *Diagnostics
+}
+
+// Renderer renders the content of an article into HTML
+type Renderer interface {
+	Render(ctx *RenderContext, source []byte) ([]byte, error)
+}
+
+// RendererFunc is a function that implements Renderer
+type RendererFunc func(ctx *RenderContext, source []byte) ([]byte, error)
+
+// Render implements Renderer.Render
+func (f RendererFunc) Render(ctx *RenderContext, source []byte) ([]byte, error) {
+	return f(ctx, source)
+}
+
+// FrontMatterRenderer is a renderer that only renders files starting with
+// front matter, other files are served as they are. It is used for formats
+// such as .txt where most files, like robots.txt, are not articles
+type FrontMatterRenderer struct {
+	Renderer
+}
+
+// DefaultRenderers are the built in renderers keyed by file extension, HTML
+// and text files are only articles when they have front matter
+var DefaultRenderers = map[string]Renderer{
+	".md":       RendererFunc(renderMarkdown),
+	".markdown": RendererFunc(renderMarkdown),
+	".html":     FrontMatterRenderer{RendererFunc(renderHTML)},
+	".txt":      FrontMatterRenderer{RendererFunc(renderText)},
+}
+
+// Renderers is a registry of renderers keyed by file extension, registered
+// renderers take precedence over DefaultRenderers. The zero value is ready to
+// use, a populated registry can be passed to NewApp
+type Renderers struct {
+	lock      sync.RWMutex
+	renderers map[string]Renderer
+}
+
+// Register registers a renderer for a file extension such as .adoc, wrap it in
+// a FrontMatterRenderer to only render files with front matter
+func (r *Renderers) Register(ext string, renderer Renderer) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.renderers == nil {
+		r.renderers = make(map[string]Renderer)
+	}
+
+	r.renderers[strings.ToLower(ext)] = renderer
+}
+
+// Get gets the renderer for a file extension
+func (r *Renderers) Get(ext string) (Renderer, bool) {
+	ext = strings.ToLower(ext)
+
+	r.lock.RLock()
+	renderer, ok := r.renderers[ext]
+	r.lock.RUnlock()
+
+	if ok {
+		return renderer, true
+	}
+
+	renderer, ok = DefaultRenderers[ext]
+	return renderer, ok
+}
+
+func renderHTML(ctx *RenderContext, source []byte) ([]byte, error) {
+	return source, nil
+}
+
+func renderText(ctx *RenderContext, source []byte) ([]byte, error) {
+	var buffer bytes.Buffer
+	buffer.WriteString("<pre>")
+	buffer.WriteString(html.EscapeString(string(source)))
+	buffer.WriteString("</pre>\n")
+	return buffer.Bytes(), nil
+}
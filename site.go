@@ -0,0 +1,228 @@
+package blog
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/gogits/git"
+)
+
+// SiteConfigFiles are the files in the root of the tree the site config is
+// read from, the first one found is used
+var SiteConfigFiles = []string{"blog.toml", "blog.json"}
+
+// Site defaults, used for settings missing from the site config
+const (
+	DefaultTitle       = "Git based blogging"
+	DefaultDescription = "Adam Talbot's code ramblings"
+	DefaultAuthor      = "Adam Talbot"
+	DefaultPageSize    = 20
+	DefaultLanguage    = "en"
+)
+
+// Site is the site config read from the tree at each commit, so branches can
+// try out different settings and one binary can host several blogs.
+//
+// BaseURL is the public url of the blog, its scheme and host are used for
+// links instead of the request host. Timezone is the location dates are
+// shown in and front matter dates without an offset are read in. Theme and
+// ThemeRef select the theme templates and assets are read from, see
+// loadTheme. Language is the language of articles without a language suffix,
+// Languages are the other languages articles are translated into.
+// BuildOverlay shows a banner linking to the build diagnostics when
+// previewing a branch or commit
+type Site struct {
+	Title         string
+	Description   string
+	Author        string
+	BaseURL       string
+	PageSize      int
+	Timezone      string
+	SummaryLength int
+	Theme         string
+	ThemeRef      string
+	Language      string
+	Languages     []string
+	Markdown      MarkdownOptions
+	BuildOverlay  bool
+
+	location *time.Location
+	baseURL  *url.URL
+}
+
+// DefaultSite gets the config used when the tree does not contain one
+func DefaultSite() *Site {
+	return &Site{
+		Title:         DefaultTitle,
+		Description:   DefaultDescription,
+		Author:        DefaultAuthor,
+		PageSize:      DefaultPageSize,
+		Timezone:      "UTC",
+		Language:      DefaultLanguage,
+		SummaryLength: DefaultSummaryLength,
+		Markdown:      DefaultMarkdownOptions,
+	}
+}
+
+// Location gets the time zone of the site
+func (s *Site) Location() *time.Location {
+	if s.location == nil {
+		return time.UTC
+	}
+	return s.location
+}
+
+// URL gets the parsed BaseURL, it is nil when the config does not set one
+func (s *Site) URL() *url.URL {
+	return s.baseURL
+}
+
+// LoadSite reads the site config from the tree, the default config is
+// returned if no config file exists
+func LoadSite(tree *git.Tree) (*Site, error) {
+	for _, name := range SiteConfigFiles {
+		data, err := readBlob(tree, name)
+		if err != nil {
+			continue
+		}
+
+		site, err := ParseSite(path.Ext(name)[1:], data)
+		if err != nil {
+			return DefaultSite(), NewDiagnostic(SeverityWarning, name, "Site config could not be parsed, using defaults", err)
+		}
+
+		return site, nil
+	}
+
+	return DefaultSite(), nil
+}
+
+// ParseSite parses a site config in the toml or json format, nested tables
+// are flattened into dotted keys such as markdown.footnotes
+func ParseSite(format string, data []byte) (*Site, error) {
+	var meta FrontMatter
+	var err error
+
+	switch format {
+	case "toml":
+		meta, err = parseMeta("toml", data, 0)
+	case "json":
+		meta, err = parseJSONMeta(data)
+	default:
+		err = fmt.Errorf("Unknown config format %q", format)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	site := DefaultSite()
+	setString(meta, "title", &site.Title)
+	setString(meta, "description", &site.Description)
+	setString(meta, "author", &site.Author)
+	setString(meta, "base_url", &site.BaseURL)
+	setInt(meta, "page_size", &site.PageSize)
+	setString(meta, "timezone", &site.Timezone)
+	setInt(meta, "summary_length", &site.SummaryLength)
+	setString(meta, "theme", &site.Theme)
+	setString(meta, "theme_ref", &site.ThemeRef)
+	setString(meta, "language", &site.Language)
+	setStrings(meta, "languages", &site.Languages)
+	setBool(meta, "build_overlay", &site.BuildOverlay)
+	site.Markdown.apply(meta, "markdown.")
+
+	if site.PageSize <= 0 {
+		return nil, fmt.Errorf("Invalid page_size %d, it must be at least 1", site.PageSize)
+	}
+
+	for _, lang := range append([]string{site.Language}, site.Languages...) {
+		if !languagePattern.MatchString(lang) {
+			return nil, fmt.Errorf("Invalid language %q", lang)
+		}
+	}
+
+	if site.location, err = time.LoadLocation(site.Timezone); err != nil {
+		return nil, fmt.Errorf("Invalid timezone %q", site.Timezone)
+	}
+
+	if site.BaseURL != "" {
+		site.baseURL, err = url.Parse(site.BaseURL)
+		if err != nil || site.baseURL.Host == "" {
+			return nil, fmt.Errorf("Invalid base_url %q, it must be an absolute url", site.BaseURL)
+		}
+	}
+
+	return site, nil
+}
+
+func parseJSONMeta(data []byte) (FrontMatter, error) {
+	var value map[string]interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+
+	meta := make(FrontMatter)
+	flattenJSONMeta(meta, "", value)
+	return meta, nil
+}
+
+func flattenJSONMeta(meta FrontMatter, prefix string, value map[string]interface{}) {
+	for key, v := range value {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			flattenJSONMeta(meta, prefix+key+".", v)
+		case []interface{}:
+			list := make([]string, 0, len(v))
+			for _, item := range v {
+				list = append(list, jsonMetaString(item))
+			}
+			meta[prefix+key] = list
+		default:
+			meta[prefix+key] = jsonMetaString(v)
+		}
+	}
+}
+
+func jsonMetaString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case nil:
+		return ""
+	}
+	return fmt.Sprint(value)
+}
+
+// setString sets s if the key is present in the metadata
+func setString(meta FrontMatter, key string, s *string) {
+	if _, ok := meta[key]; ok {
+		*s = meta.String(key)
+	}
+}
+
+// setStrings sets s if the key is present in the metadata
+func setStrings(meta FrontMatter, key string, s *[]string) {
+	if _, ok := meta[key]; ok {
+		*s = meta.Strings(key)
+	}
+}
+
+// setBool sets b if the key is present in the metadata
+func setBool(meta FrontMatter, key string, b *bool) {
+	if _, ok := meta[key]; ok {
+		*b = meta.Bool(key)
+	}
+}
+
+// setInt sets i if the key is present in the metadata and is a valid number
+func setInt(meta FrontMatter, key string, i *int) {
+	if n, err := strconv.Atoi(meta.String(key)); err == nil {
+		*i = n
+	}
+}
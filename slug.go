@@ -0,0 +1,27 @@
+package blog
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Slugify converts a string into a url friendly slug, letters and digits are
+// kept and everything else is collapsed into single dashes
+func Slugify(s string) string {
+	var slug []rune
+	dash := false
+
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && len(slug) > 0 {
+				slug = append(slug, '-')
+			}
+			slug = append(slug, r)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+
+	return string(slug)
+}
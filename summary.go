@@ -0,0 +1,148 @@
+package blog
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/kennygrant/sanitize"
+	"golang.org/x/net/html"
+)
+
+// MoreMarker marks the end of the summary in the content of an article
+const MoreMarker = "<!--more-->"
+
+// DefaultSummaryLength is the number of words in a summary when the site
+// config does not set summary_length
+const DefaultSummaryLength = 70
+
+// voidElements are elements that have no closing tag
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true,
+	"hr": true, "img": true, "input": true, "link": true, "meta": true,
+	"param": true, "source": true, "track": true, "wbr": true,
+}
+
+// Summarize creates the summary shown in article listings from rendered
+// HTML, the content before a <!--more--> marker is used if present, otherwise
+// the content is cut after the given number of words. more is true if the
+// summary does not contain the whole article
+func Summarize(data []byte, words int) (summary []byte, more bool) {
+	if i := bytes.Index(data, []byte(MoreMarker)); i >= 0 {
+		summary, _ = truncateHTML(sanitizeSummary(data[:i]), -1)
+		return summary, true
+	}
+
+	return truncateHTML(sanitizeSummary(data), words)
+}
+
+// summaryTags are the tags kept in summaries, links keep only their href
+var summaryTags = []string{
+	"h1", "p", "br", "b", "i", "strong", "em", "code", "pre", "a",
+	"ul", "ol", "li", "blockquote",
+}
+
+func sanitizeSummary(data []byte) string {
+	s, _ := sanitize.HTMLAllowing(string(data), summaryTags, []string{"href"})
+	s = strings.Replace(s, "<h1>", "<h3>", -1)
+	s = strings.Replace(s, "</h1>", "</h3>", -1)
+	return s
+}
+
+// truncateHTML cuts HTML after a number of words, tags left open are closed
+// so the result is well formed. A negative limit only closes open tags
+func truncateHTML(s string, limit int) ([]byte, bool) {
+	var out bytes.Buffer
+	var open []string
+
+	tokenizer := html.NewTokenizer(strings.NewReader(s))
+	count := 0
+	truncated := false
+
+	// mark is the end of the last text or closing tag, tags opened after it
+	// are dropped if the summary is cut before they contain any text
+	mark, marked := 0, 0
+
+tokens:
+	for {
+		tt := tokenizer.Next()
+		raw := tokenizer.Raw()
+
+		switch tt {
+		case html.ErrorToken:
+			break tokens
+
+		case html.StartTagToken:
+			name, _ := tokenizer.TagName()
+			if !voidElements[string(name)] {
+				open = append(open, string(name))
+			}
+			out.Write(raw)
+
+		case html.EndTagToken:
+			name, _ := tokenizer.TagName()
+			for i := len(open) - 1; i >= 0; i-- {
+				if open[i] == string(name) {
+					open = open[:i]
+					out.Write(raw)
+					mark, marked = out.Len(), len(open)
+					break
+				}
+			}
+
+		case html.TextToken:
+			if limit < 0 {
+				out.Write(raw)
+				continue
+			}
+
+			end, n, cut := cutWords(raw, limit-count)
+			if end > 0 {
+				out.Write(raw[:end])
+				mark, marked = out.Len(), len(open)
+			}
+			count += n
+
+			if cut {
+				if end == 0 {
+					out.Truncate(mark)
+					open = open[:marked]
+				}
+				truncated = true
+				break tokens
+			}
+
+		default:
+			out.Write(raw)
+		}
+	}
+
+	for i := len(open) - 1; i >= 0; i-- {
+		out.WriteString("</" + open[i] + ">")
+	}
+
+	return out.Bytes(), truncated
+}
+
+// cutWords finds the end of the first limit words in text. cut is true if
+// words remain after the limit
+func cutWords(text []byte, limit int) (end int, words int, cut bool) {
+	inWord := false
+
+	for i, c := range text {
+		space := c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+
+		switch {
+		case !space && !inWord:
+			if words == limit {
+				return end, words, true
+			}
+			inWord = true
+			words++
+		case space && inWord:
+			inWord = false
+			end = i
+		}
+	}
+
+	return len(text), words, false
+}
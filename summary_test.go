@@ -0,0 +1,109 @@
+package blog
+
+import "testing"
+
+func TestSummarize(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		words   int
+		summary string
+		more    bool
+	}{
+		{
+			name:    "short",
+			data:    "<p>One two</p>",
+			words:   5,
+			summary: "<p>One two</p>",
+		},
+		{
+			name:    "exact length",
+			data:    "<p>One two three</p>",
+			words:   3,
+			summary: "<p>One two three</p>",
+		},
+		{
+			name:    "cut closes tags",
+			data:    "<p>One <em>two three</em> four</p>",
+			words:   2,
+			summary: "<p>One <em>two</em></p>",
+			more:    true,
+		},
+		{
+			name:    "cut between paragraphs drops empty tags",
+			data:    "<p>One two</p><p>three</p>",
+			words:   2,
+			summary: "<p>One two</p>",
+			more:    true,
+		},
+		{
+			name:    "more marker",
+			data:    "<p>One two three</p>\n<!--more-->\n<p>four</p>",
+			words:   1,
+			summary: "<p>One two three</p>\n",
+			more:    true,
+		},
+		{
+			name:    "more marker inside a tag",
+			data:    "<p>One <strong>two<!--more--> three</strong></p>",
+			words:   10,
+			summary: "<p>One <strong>two</strong></p>",
+			more:    true,
+		},
+		{
+			name:    "entities are kept whole",
+			data:    "<p>Fish &amp; chips &lt;3 later</p>",
+			words:   3,
+			summary: "<p>Fish &amp; chips</p>",
+			more:    true,
+		},
+		{
+			name:    "multi-byte text",
+			data:    "<p>Привет мир, 世界 ok</p>",
+			words:   3,
+			summary: "<p>Привет мир, 世界</p>",
+			more:    true,
+		},
+		{
+			name:    "headings are demoted and other tags dropped",
+			data:    "<h1>Title</h1><div><img src=\"x.png\">Text</div>",
+			words:   10,
+			summary: "<h3>Title</h3>Text",
+		},
+	}
+
+	for _, test := range tests {
+		summary, more := Summarize([]byte(test.data), test.words)
+
+		if string(summary) != test.summary {
+			t.Errorf("%s: expected summary %q, got %q", test.name, test.summary, summary)
+		}
+		if more != test.more {
+			t.Errorf("%s: expected more to be %v, got %v", test.name, test.more, more)
+		}
+	}
+}
+
+func TestCutWords(t *testing.T) {
+	tests := []struct {
+		text  string
+		limit int
+		end   int
+		words int
+		cut   bool
+	}{
+		{"", 3, 0, 0, false},
+		{"one two", 3, 7, 2, false},
+		{"one two three", 2, 7, 2, true},
+		{"  one", 0, 0, 0, true},
+		{"  ", 0, 2, 0, false},
+		{"é ü ö", 2, 5, 2, true},
+	}
+
+	for _, test := range tests {
+		end, words, cut := cutWords([]byte(test.text), test.limit)
+		if end != test.end || words != test.words || cut != test.cut {
+			t.Errorf("%q limit %d: expected (%d, %d, %v), got (%d, %d, %v)", test.text, test.limit, test.end, test.words, test.cut, end, words, cut)
+		}
+	}
+}
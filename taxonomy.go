@@ -0,0 +1,92 @@
+package blog
+
+import (
+	"sort"
+	"time"
+)
+
+// Term is a single tag or category
+type Term struct {
+	Name     string
+	Slug     string
+	Articles Index
+}
+
+// Count is the number of articles with the term
+func (t *Term) Count() int {
+	return len(t.Articles)
+}
+
+// Taxonomy groups articles by term, for example by tag, it is keyed by the
+// term slug
+type Taxonomy map[string]*Term
+
+// Add adds an article to each of the named terms, names that share a slug
+// such as go and Go only add the article once
+func (t Taxonomy) Add(article Article, names []string) {
+	added := make(map[string]bool, len(names))
+	for _, name := range names {
+		slug := Slugify(name)
+		if slug == "" || added[slug] {
+			continue
+		}
+		added[slug] = true
+
+		term, ok := t[slug]
+		if !ok {
+			term = &Term{Name: name, Slug: slug}
+			t[slug] = term
+		}
+
+		term.Articles = append(term.Articles, article)
+	}
+}
+
+// Sort sorts the articles of every term
+func (t Taxonomy) Sort() {
+	for _, term := range t {
+		sort.Sort(term.Articles)
+	}
+}
+
+// Published filters the taxonomy to articles published at the given time,
+// terms without any published articles are removed
+func (t Taxonomy) Published(now time.Time) Taxonomy {
+	published := make(Taxonomy, len(t))
+	for slug, term := range t {
+		if articles := term.Articles.Published(now); len(articles) > 0 {
+			published[slug] = &Term{
+				Name:     term.Name,
+				Slug:     term.Slug,
+				Articles: articles,
+			}
+		}
+	}
+	return published
+}
+
+// Terms lists the terms sorted by name
+func (t Taxonomy) Terms() []*Term {
+	terms := make([]*Term, 0, len(t))
+	for _, term := range t {
+		terms = append(terms, term)
+	}
+
+	sort.Sort(termsByName(terms))
+
+	return terms
+}
+
+type termsByName []*Term
+
+func (t termsByName) Len() int {
+	return len(t)
+}
+
+func (t termsByName) Less(i, j int) bool {
+	return t[i].Slug < t[j].Slug
+}
+
+func (t termsByName) Swap(i, j int) {
+	t[i], t[j] = t[j], t[i]
+}
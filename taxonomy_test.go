@@ -0,0 +1,56 @@
+package blog
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTaxonomyAdd(t *testing.T) {
+	tests := []struct {
+		name  string
+		tags  []string
+		terms map[string]int
+	}{
+		{"distinct", []string{"go", "blog"}, map[string]int{"go": 1, "blog": 1}},
+		{"same slug", []string{"go", "Go", " GO "}, map[string]int{"go": 1}},
+		{"symbols", []string{"C++", "C", "c#"}, map[string]int{"c": 1}},
+		{"empty slug", []string{"++", ""}, map[string]int{}},
+	}
+
+	for _, test := range tests {
+		taxonomy := make(Taxonomy)
+		taxonomy.Add(Article{Name: "one"}, test.tags)
+
+		terms := make(map[string]int)
+		for slug, term := range taxonomy {
+			terms[slug] = term.Count()
+		}
+
+		if !reflect.DeepEqual(terms, test.terms) {
+			t.Errorf("%s: expected terms %v, got %v", test.name, test.terms, terms)
+		}
+	}
+}
+
+func TestTaxonomyAddArticles(t *testing.T) {
+	taxonomy := make(Taxonomy)
+	taxonomy.Add(Article{Name: "one"}, []string{"Go", "go"})
+	taxonomy.Add(Article{Name: "two"}, []string{"go"})
+
+	term := taxonomy["go"]
+	if term == nil {
+		t.Fatalf("expected a go term, got %v", taxonomy)
+	}
+
+	if term.Name != "Go" || term.Slug != "go" {
+		t.Errorf("expected the first name to be kept, got %q with slug %q", term.Name, term.Slug)
+	}
+
+	var names []string
+	for _, article := range term.Articles {
+		names = append(names, article.Name)
+	}
+	if expected := []string{"one", "two"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected articles %v, got %v", expected, names)
+	}
+}
@@ -1,11 +1,110 @@
 package blog
 
-import (
-	"html/template"
-)
+// LayoutTemplate is the default base layout, pages render it with
+// {{template "layout" .}} and override its title, style and content blocks
+const LayoutTemplate = `{{define "layout"}}<!doctype html><html lang="{{block "lang" .}}{{.Site.Language}}{{end}}"><head> <meta charset="utf-8"> <title>{{block "title" .}}{{.Site.Title}}{{end}}</title> <meta name="description" content="{{.Site.Description}}"> <meta name="author" content="{{.Site.Author}}">{{block "head" .}}{{end}} <style>@import url(https://fonts.googleapis.com/css?family=Open+Sans:400,800); html, body{padding: 0; margin: 0; font-family: 'Open Sans', sans-serif;}.header{background: #222; padding: 0.8em 1em; color: #CCC;}.header:after{content:''; display:block; clear:both;}.header__logo{display: inline-block; text-align: center; font-weight: 900; font-family: monospace; font-size: 25px; border: 2px solid #CCCCCC; padding: 2px 5px; margin: 0 0.8em; vertical-align: middle;}.header__title{display: inline-block; vertical-align: middle;}.header__git{display: inline-block; float: right; font-style: italic; font-family: monospace;}.home{display:block; margin: 1em;}.article{border: 2px solid #222; margin: 1em; padding: 1em;}.section{margin: 1em;}.pagination{text-align: center;}.pagination a{text-decoration: none;}.broken-links{margin: 1em; padding: 0.5em 1em; background: #ffeef0; border: 2px solid #b31d28;}.build-overlay{position: fixed; bottom: 1em; right: 1em; padding: 0.5em 1em; background: #b31d28; color: #fff; text-decoration: none;}.header__pages{display: inline-block; vertical-align: middle; margin-left: 1em;}.header__pages a{color: #CCC; margin: 0 0.5em;}.code{margin: 1em 0;}.code__title{font-family: monospace; background: #e8e8e8; padding: 0.3em 0.8em;}.highlight{background: #f5f5f5; padding: 0.8em 0; margin: 0; overflow: auto;}.highlight .line{display: block; padding: 0 0.8em;}.highlight .line--highlight{background: #fff3c4;}.highlight--numbered{counter-reset: line;}.highlight--numbered .line:before{counter-increment: line; content: counter(line); display: inline-block; width: 2.5em; margin-right: 1em; color: #999; text-align: right;}.hl-keyword{color: #07a;}.hl-type{color: #905;}.hl-string{color: #690;}.hl-number{color: #905;}.hl-comment{color: #708090; font-style: italic;}.hl-key{color: #a67f59;}.hl-variable{color: #e90;}.hl-inserted{color: #22863a; background: #e6ffed;}.hl-deleted{color: #b31d28; background: #ffeef0;}.hl-meta{color: #6f42c1;}.contents{float: right; margin: 0 0 1em 1em; padding: 0.5em 1em; border-left: 2px solid #222;}.toc{list-style: none; padding-left: 1em; margin: 0;}.wikilink--broken{color: #b31d28; text-decoration: line-through dotted;}.backlinks{margin-top: 1em; border-top: 1px solid #ccc;}.translations{margin-top: 0.5em;}.translations a{margin-right: 0.5em;}{{block "style" .}}{{end}}</style></head><body>{{template "header" .}}{{block "content" .}}{{end}}{{template "footer" .}}</body></html>{{end}}`
+
+// HeaderTemplate is the default header partial
+const HeaderTemplate = `{{define "header"}} <header class="header"> <div class="header__logo">B L<br/>O G</div><h1 class="header__title">{{.Site.Title}}</h1> {{template "page-nav" .}} <div class="header__git">git clone {{.GitURL}}</div></header>{{end}}`
+
+// NavTemplate is the default navigation partial
+const NavTemplate = `{{define "nav"}} <a class="home" href="{{.BaseURL}}">Home</a>{{end}}`
+
+// PageNavTemplate is the default partial linking to the standalone pages
+const PageNavTemplate = `{{define "page-nav"}}{{with pages}}<nav class="header__pages">{{range .}}<a href="{{pageURL $.BaseURL .Name}}">{{.Title}}</a>{{end}}</nav>{{end}}{{end}}`
+
+// FooterTemplate is the default footer partial, it is empty so a tree can
+// add a footer without overriding the layout
+const FooterTemplate = `{{define "footer"}}{{end}}`
+
+// BrokenLinksTemplate is the default partial listing broken links when
+// previewing
+const BrokenLinksTemplate = `{{define "broken-links"}}{{if .BrokenLinks}}<div class="broken-links"><b>Broken links</b><ul>{{range .BrokenLinks}}<li>{{.Source}}: <code>{{.Link}}</code> ({{.Reason}})</li>{{end}}</ul></div>{{end}}{{end}}`
+
+// BuildOverlayTemplate is the default partial showing a banner that links to
+// the build diagnostics when previewing
+const BuildOverlayTemplate = `{{define "build-overlay"}}{{if .Diagnostics}}<a class="build-overlay" href="{{.BaseURL}}_build/">{{len .Diagnostics}} build problem{{if gt (len .Diagnostics) 1}}s{{end}}{{with .Diagnostics.Errors}}, {{.}} error{{if gt . 1}}s{{end}}{{end}}</a>{{end}}{{end}}`
 
 // ArticleTemplate is the default article template
-var ArticleTemplate, _ = template.New("article").Parse(`<!doctype html><html lang="en"><head> <meta charset="utf-8"> <title>Git based blogging</title> <meta name="description" content="Adam Talbot's code ramblings"> <meta name="author" content="Adam Talbot"> <link rel="stylesheet" href="https://cdnjs.cloudflare.com/ajax/libs/prism/1.4.1/themes/prism.min.css"> <style>@import url(https://fonts.googleapis.com/css?family=Open+Sans:400,800); html, body{padding: 0; margin: 0; font-family: 'Open Sans', sans-serif;}.header{background: #222; padding: 0.8em 1em; color: #CCC;}.header:after{content:''; display:block; clear:both;}.header__logo{display: inline-block; text-align: center; font-weight: 900; font-family: monospace; font-size: 25px; border: 2px solid #CCCCCC; padding: 2px 5px; margin: 0 0.8em; vertical-align: middle;}.header__title{display: inline-block; vertical-align: middle;}.header__git{display: inline-block; float: right; font-style: italic; font-family: monospace;}.home{display:block; margin: 1em;}.article{border: 2px solid #222; margin: 1em; padding: 1em;}</style></head><body> <header class="header"> <div class="header__logo">B L<br/>O G</div><h1 class="header__title">Git based blogging</h1> <div class="header__git">git clone {{.GitURL}}</div></header> <a class="home" href="{{.BaseURL}}">Home</a> <div class="article"> <p>{{.Article.Full}}</p><i>Posted on {{.Article.Mod}}</i> </div><script type="text/javascript" src="https://cdnjs.cloudflare.com/ajax/libs/prism/1.4.1/prism.min.js"></script> <script type="text/javascript" src="https://cdnjs.cloudflare.com/ajax/libs/prism/1.4.1/components/prism-go.min.js"></script></body></html>`)
+const ArticleTemplate = `{{template "layout" .}}{{define "lang"}}{{.Article.Language}}{{end}}{{define "head"}}{{if .Translations}}{{range .Alternates}} <link rel="alternate" hreflang="{{.Language}}" href="{{.Link}}">{{end}}{{end}}{{end}}{{define "content"}}{{template "build-overlay" .}}{{template "broken-links" .}}{{template "nav" .}} <div class="article">{{if .Article.TOC}} <nav class="contents">{{.Article.TOC.HTML}}</nav>{{end}} <p>{{.Article.Full}}</p><i>Posted on {{date "2 January 2006" .Article.Date}}{{if .Article.Author}} by <a href="{{.BaseURL}}authors/{{.Article.Author.ID}}/">{{.Article.Author.Name}}</a>{{end}}{{if .Article.Mod.After .Article.Date}}, updated {{ago .Article.Mod}}{{end}}</i>{{with .Translations}} <div class="translations">Also in {{range .}}<a href="{{.Link}}" hreflang="{{.Language}}" lang="{{.Language}}">{{.Title}}</a>{{end}}</div>{{end}}{{if .Article.Backlinks}} <div class="backlinks"><b>Linked from</b><ul>{{range .Article.Backlinks}}<li><a href="{{articleURL $.BaseURL .}}">{{.Title}}</a></li>{{end}}</ul></div>{{end}} </div>{{end}}`
 
 // IndexTemplate is the default index template
-var IndexTemplate, _ = template.New("index").Parse(`<!doctype html><html lang="en"><head> <meta charset="utf-8"> <title>Git based blogging</title> <meta name="description" content="Adam Talbot's code ramblings"> <meta name="author" content="Adam Talbot"> <style>@import url(https://fonts.googleapis.com/css?family=Open+Sans:400,800); html, body{padding: 0; margin: 0; font-family: 'Open Sans', sans-serif;}.header{background: #222; padding: 0.8em 1em; color: #CCC;}.header:after{content:''; display:block; clear:both;}.header__logo{display: inline-block; text-align: center; font-weight: 900; font-family: monospace; font-size: 25px; border: 2px solid #CCCCCC; padding: 2px 5px; margin: 0 0.8em; vertical-align: middle;}.header__title{display: inline-block; vertical-align: middle;}.header__git{display: inline-block; float: right; font-style: italic; font-family: monospace;}.article{border: 2px solid #222; margin: 1em; padding: 1em;}.pagination{text-align: center;}.pagination a{text-decoration: none;}</style></head><body> <header class="header"> <div class="header__logo">B L<br/>O G</div><h1 class="header__title">Git based blogging</h1> <div class="header__git">git clone {{.GitURL}}</div></header>{{range $article :=.Articles}}<div class="article"> <p>{{$article.Preview $.BaseURL}}</p><i>Posted on {{$article.Mod}}</i> </div>{{end}}<div class="pagination">{{.Pagination}}</div></body></html>`)
+const IndexTemplate = `{{template "layout" .}}{{define "lang"}}{{.Language}}{{end}}{{define "content"}}{{template "build-overlay" .}}{{template "broken-links" .}}{{range $article :=.Articles}}<div class="article"> <p>{{$article.Preview $.BaseURL}}</p><i>Posted on {{date "2 January 2006" $article.Date}}</i> </div>{{end}}<div class="pagination">{{.Pagination}}</div>{{end}}`
+
+// SectionTemplate is the default section template
+const SectionTemplate = `{{template "layout" .}}{{define "content"}}{{template "nav" .}} <h2 class="section">{{.Section}}</h2>{{range $article :=.Articles}}<div class="article"> <p>{{$article.Preview $.BaseURL}}</p><i>Posted on {{date "2 January 2006" $article.Date}}</i> </div>{{end}}<div class="pagination">{{.Pagination}}</div>{{end}}`
+
+// TagsTemplate is the default tags template, also used for categories
+const TagsTemplate = `{{template "layout" .}}{{define "style"}}.tags{margin: 1em;}{{end}}{{define "content"}}{{template "nav" .}} <h2 class="section">{{.Taxonomy}}</h2><ul class="tags">{{range $tag :=.Tags}}<li><a href="{{$.BaseURL}}{{$.Taxonomy}}/{{$tag.Slug}}/">{{$tag.Name}}</a> ({{$tag.Count}})</li>{{end}}</ul>{{end}}`
+
+// TagTemplate is the default tag template, also used for categories
+const TagTemplate = `{{template "layout" .}}{{define "content"}}{{template "nav" .}} <h2 class="section">{{.Tag.Name}}</h2>{{range $article :=.Articles}}<div class="article"> <p>{{$article.Preview $.BaseURL}}</p><i>Posted on {{date "2 January 2006" $article.Date}}</i> </div>{{end}}<div class="pagination">{{.Pagination}}</div>{{end}}`
+
+// AuthorTemplate is the default author template
+const AuthorTemplate = `{{template "layout" .}}{{define "content"}}{{template "nav" .}} <h2 class="section">{{.Author.Author.Name}}</h2><div class="section">{{.Author.FullBio}}</div>{{range $article :=.Articles}}<div class="article"> <p>{{$article.Preview $.BaseURL}}</p><i>Posted on {{date "2 January 2006" $article.Date}}</i> </div>{{end}}{{if .Author.Contributions}}<h3 class="section">Contributed to</h3>{{range $article :=.Author.Contributions}}<div class="article"> <p>{{$article.Preview $.BaseURL}}</p><i>Posted on {{date "2 January 2006" $article.Date}}</i> </div>{{end}}{{end}}<div class="pagination">{{.Pagination}}</div>{{end}}`
+
+// HistoryTemplate is the default article history template
+const HistoryTemplate = `{{template "layout" .}}{{define "style"}}.revision{margin: 1em;}.revision__id{font-family: monospace;}{{end}}{{define "content"}}{{template "nav" .}} <h2 class="section">History of <a href="{{articleURL .BaseURL .Article}}">{{.Article.Title}}</a></h2>{{range $revision :=.Revisions}}<div class="revision"> <a class="revision__id" href="{{$revision.URL}}">{{$revision.ShortID}}</a> {{$revision.Summary}}<br/><i>{{$revision.Author.Name}}, {{ago $revision.Date}}</i>{{if $revision.Parent}} <a href="{{articleURL $.BaseURL $.Article}}diff/{{$revision.Parent}}/{{$revision.ID}}/">(Changes)</a>{{end}} </div>{{end}}<div class="pagination">{{.Pagination}}</div>{{end}}`
+
+// DiffTemplate is the default article diff template
+const DiffTemplate = `{{template "layout" .}}{{define "style"}}.diff{margin: 1em; font-family: monospace; white-space: pre-wrap; border-collapse: collapse; width: calc(100% - 2em);}.diff td{vertical-align: top; padding: 0 0.5em;}.diff__number{color: #999; text-align: right; width: 3em;}.diff__line--insert{background: #e6ffed;}.diff__line--delete{background: #ffeef0;}{{end}}{{define "content"}}{{template "nav" .}} <h2 class="section">Changes to <a href="{{articleURL .BaseURL .Article}}">{{.Article.Title}}</a></h2><p class="section"><a href="{{.From.URL}}">{{.From.ShortID}}</a> {{.From.Summary}} &rarr; <a href="{{.To.URL}}">{{.To.ShortID}}</a> {{.To.Summary}}<br/>{{if .Split}}<a href="?view=unified">Unified view</a>{{else}}<a href="?view=split">Side by side view</a>{{end}}</p>{{if .Split}}<table class="diff">{{range $row :=.Rows}}<tr>{{with $row.Left}}<td class="diff__number">{{.Old}}</td><td class="{{.Class}}">{{.Text}}</td>{{else}}<td class="diff__number"></td><td></td>{{end}}{{with $row.Right}}<td class="diff__number">{{.New}}</td><td class="{{.Class}}">{{.Text}}</td>{{else}}<td class="diff__number"></td><td></td>{{end}}</tr>{{end}}</table>{{else}}<table class="diff">{{range $line :=.Lines}}<tr class="{{$line.Class}}"><td class="diff__number">{{if $line.Old}}{{$line.Old}}{{end}}</td><td class="diff__number">{{if $line.New}}{{$line.New}}{{end}}</td><td>{{$line.Prefix}} {{$line.Text}}</td></tr>{{end}}</table>{{end}}{{end}}`
+
+// PageTemplate is the default standalone page template
+const PageTemplate = `{{template "layout" .}}{{define "content"}}{{template "build-overlay" .}}{{template "broken-links" .}}{{template "nav" .}} <div class="article">{{if .Page.TOC}} <nav class="contents">{{.Page.TOC.HTML}}</nav>{{end}} {{.Page.Full}}</div>{{end}}`
+
+// BuildTemplate is the default build diagnostics template
+const BuildTemplate = `{{template "layout" .}}{{define "style"}}.diagnostics{margin: 1em; border-collapse: collapse;}.diagnostics td{padding: 0.2em 0.5em; vertical-align: top;}.diagnostic__severity{font-weight: bold;}.diagnostic--error .diagnostic__severity{color: #b31d28;}.diagnostic--warning .diagnostic__severity{color: #b08800;}.diagnostic__file{font-family: monospace;}{{end}}{{define "content"}}{{template "nav" .}} <h2 class="section">Build of {{.Commit}}</h2>{{if .Diagnostics}}<table class="diagnostics">{{range .Diagnostics}}<tr class="diagnostic diagnostic--{{.Severity}}"><td class="diagnostic__severity">{{.Severity}}</td><td class="diagnostic__file">{{.File}}{{if .Line}}:{{.Line}}{{end}}</td><td>{{.Message}}</td></tr>{{end}}</table>{{else}}<p class="section">No problems found</p>{{end}}{{template "broken-links" .}}{{end}}`
+
+// NotFoundTemplate is the default template for 404 errors
+const NotFoundTemplate = `{{template "layout" .}}{{define "title"}}Not found - {{.Site.Title}}{{end}}{{define "content"}}{{template "nav" .}} <div class="article"> <h2>Not found</h2><p>{{.Message}}</p><i class="error__request">Request {{.RequestID}}</i> </div>{{end}}`
+
+// ErrorTemplate is the default template for every other error
+const ErrorTemplate = `{{template "layout" .}}{{define "title"}}{{.StatusText}} - {{.Site.Title}}{{end}}{{define "content"}}{{template "nav" .}} <div class="article"> <h2>{{.Status}} {{.StatusText}}</h2><p>{{.Message}}</p><i class="error__request">Request {{.RequestID}}</i> </div>{{end}}`
+
+// DefaultTemplates maps template file names to the default sources, a tree
+// overrides any of them with a file of the same name in its templates
+// directory
+var DefaultTemplates = map[string]string{
+	"layout.tpl":        LayoutTemplate,
+	"header.tpl":        HeaderTemplate,
+	"nav.tpl":           NavTemplate,
+	"page-nav.tpl":      PageNavTemplate,
+	"footer.tpl":        FooterTemplate,
+	"broken-links.tpl":  BrokenLinksTemplate,
+	"build-overlay.tpl": BuildOverlayTemplate,
+
+	"index.tpl":   IndexTemplate,
+	"article.tpl": ArticleTemplate,
+	"section.tpl": SectionTemplate,
+	"author.tpl":  AuthorTemplate,
+	"history.tpl": HistoryTemplate,
+	"diff.tpl":    DiffTemplate,
+	"page.tpl":    PageTemplate,
+	"build.tpl":   BuildTemplate,
+	"404.tpl":     NotFoundTemplate,
+	"error.tpl":   ErrorTemplate,
+
+	"tags.tpl":       TagsTemplate,
+	"tag.tpl":        TagTemplate,
+	"categories.tpl": TagsTemplate,
+	"category.tpl":   TagTemplate,
+}
+
+// PageTemplates are the templates rendered by handlers, every other template
+// in the set is a layout or partial shared between the pages
+var PageTemplates = []string{
+	"index.tpl",
+	"article.tpl",
+	"section.tpl",
+	"author.tpl",
+	"history.tpl",
+	"diff.tpl",
+	"page.tpl",
+	"build.tpl",
+	"404.tpl",
+	"error.tpl",
+	"tags.tpl",
+	"tag.tpl",
+	"categories.tpl",
+	"category.tpl",
+}
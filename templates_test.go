@@ -0,0 +1,151 @@
+package blog
+
+import (
+	"bytes"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestCompileTemplates(t *testing.T) {
+	source := func(path, text string) templateSource {
+		name := path
+		if i := strings.LastIndex(path, TemplateDir+"/"); i >= 0 {
+			name = path[i+len(TemplateDir)+1:]
+		}
+		return templateSource{Name: name, Path: path, Source: text}
+	}
+
+	tests := []struct {
+		name        string
+		theme       []templateSource
+		root        []templateSource
+		tree        []templateSource
+		contains    []string
+		excludes    []string
+		defaults    bool
+		diagnostics []string
+	}{
+		{
+			name:     "defaults",
+			contains: []string{"<!doctype html>", `<div class="header__logo">`, "<h2>Not found</h2><p>gone</p>"},
+		},
+		{
+			name:     "tree partial replaces only the partial",
+			tree:     []templateSource{source("templates/header.tpl", `{{define "header"}}<header>tree</header>{{end}}`)},
+			contains: []string{"<!doctype html>", "<header>tree</header>", "<h2>Not found</h2>"},
+			excludes: []string{`<div class="header__logo">`},
+		},
+		{
+			name: "tree overrides the theme",
+			theme: []templateSource{
+				source("themes/a/templates/header.tpl", `{{define "header"}}<header>theme</header>{{end}}`),
+				source("themes/a/templates/footer.tpl", `{{define "footer"}}<footer>theme</footer>{{end}}`),
+			},
+			tree:     []templateSource{source("templates/header.tpl", `{{define "header"}}<header>tree</header>{{end}}`)},
+			contains: []string{"<header>tree</header>", "<footer>theme</footer>"},
+			excludes: []string{"<header>theme</header>"},
+		},
+		{
+			name:     "page overrides the content block",
+			root:     []templateSource{source("404.tpl", `{{template "layout" .}}{{define "content"}}<main>root</main>{{end}}`)},
+			contains: []string{"<!doctype html>", `<div class="header__logo">`, "<main>root</main>"},
+			excludes: []string{"<h2>Not found</h2>"},
+		},
+		{
+			name:     "root pages override the theme",
+			theme:    []templateSource{source("themes/a/templates/404.tpl", `{{template "layout" .}}{{define "content"}}<main>theme</main>{{end}}`)},
+			root:     []templateSource{source("404.tpl", `{{template "layout" .}}{{define "content"}}<main>root</main>{{end}}`)},
+			contains: []string{"<main>root</main>"},
+			excludes: []string{"<main>theme</main>"},
+		},
+		{
+			name:     "templates directory overrides root pages",
+			root:     []templateSource{source("404.tpl", `{{template "layout" .}}{{define "content"}}<main>root</main>{{end}}`)},
+			tree:     []templateSource{source("templates/404.tpl", `{{template "layout" .}}{{define "content"}}<main>tree</main>{{end}}`)},
+			contains: []string{"<main>tree</main>"},
+			excludes: []string{"<main>root</main>"},
+		},
+		{
+			name:        "broken partial falls back to the defaults",
+			tree:        []templateSource{source("templates/header.tpl", `{{define "header"}}{{if}}{{end}}`)},
+			contains:    []string{`<div class="header__logo">`},
+			defaults:    true,
+			diagnostics: []string{"templates/header.tpl"},
+		},
+		{
+			name: "broken page falls back to the default page",
+			root: []templateSource{source("404.tpl", `{{template "layout" .}}{{define "content"}}{{end`)},
+			tree: []templateSource{source("templates/header.tpl", `{{define "header"}}<header>tree</header>{{end}}`)},
+			contains: []string{
+				`<div class="header__logo">`,
+				"<h2>Not found</h2>",
+			},
+			diagnostics: []string{"404.tpl"},
+		},
+	}
+
+	site := DefaultSite()
+	base, _ := url.Parse("http://example.com/")
+
+	for _, test := range tests {
+		var diagnostics Diagnostics
+		templates := compileTemplates(test.theme, test.root, test.tree, templateFuncs(site, nil, nil), &diagnostics)
+
+		for _, name := range PageTemplates {
+			if templates[name] == nil {
+				t.Errorf("%s: expected a %s template", test.name, name)
+			}
+		}
+
+		if defaults := templates["error.tpl"] == defaultTemplates["error.tpl"]; defaults != test.defaults {
+			t.Errorf("%s: expected the default templates to be used to be %v", test.name, test.defaults)
+		}
+
+		var files []string
+		for _, diagnostic := range diagnostics {
+			if diagnostic.Severity != SeverityError {
+				t.Errorf("%s: expected an error, got %s", test.name, diagnostic.Severity)
+			}
+			files = append(files, diagnostic.File)
+		}
+		if strings.Join(files, " ") != strings.Join(test.diagnostics, " ") {
+			t.Errorf("%s: expected diagnostics for %v, got %v", test.name, test.diagnostics, files)
+		}
+
+		var buffer bytes.Buffer
+		model := &ErrorModel{BaseURL: base, Site: site, Status: 404, Message: "gone"}
+		if err := templates["404.tpl"].Execute(&buffer, model); err != nil {
+			t.Errorf("%s: could not execute 404.tpl: %v", test.name, err)
+			continue
+		}
+
+		html := buffer.String()
+		for _, expected := range test.contains {
+			if !strings.Contains(html, expected) {
+				t.Errorf("%s: expected %q in %q", test.name, expected, html)
+			}
+		}
+		for _, unexpected := range test.excludes {
+			if strings.Contains(html, unexpected) {
+				t.Errorf("%s: did not expect %q in %q", test.name, unexpected, html)
+			}
+		}
+	}
+}
+
+func TestCompileTemplatesPagesAreIsolated(t *testing.T) {
+	root := []templateSource{{Name: "404.tpl", Path: "404.tpl", Source: `{{template "layout" .}}{{define "content"}}<main>root</main>{{end}}`}}
+	templates := compileTemplates(nil, root, nil, templateFuncs(DefaultSite(), nil, nil), nil)
+
+	base, _ := url.Parse("http://example.com/")
+
+	var buffer bytes.Buffer
+	if err := templates["error.tpl"].Execute(&buffer, &ErrorModel{BaseURL: base, Site: DefaultSite(), Status: 500}); err != nil {
+		t.Fatalf("could not execute error.tpl: %v", err)
+	}
+
+	if html := buffer.String(); strings.Contains(html, "<main>root</main>") || !strings.Contains(html, "<h2>500") {
+		t.Errorf("expected the content block of 404.tpl not to leak into error.tpl, got %q", html)
+	}
+}
@@ -0,0 +1,124 @@
+package blog
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/gogits/git"
+)
+
+// ThemesDir is the directory of the tree themes are read from, a site with
+// theme = "name" in its config uses themes/name/
+const ThemesDir = "themes"
+
+// theme is the tree templates and assets are read from when the content tree
+// does not have them, Path locates the theme in diagnostics. Ref and Commit
+// are the theme_ref and the commit it pointed at when the theme was loaded
+type theme struct {
+	Tree *git.Tree
+	Path string
+
+	Ref    string
+	Commit string
+}
+
+// loadTheme finds the theme of a site, nil is returned when the site does
+// not use one. The theme is read from themes/<theme>/ of the tree, or from
+// the ref given by theme_ref, such as refs/heads/theme. A theme ref holds a
+// single theme at its root, or several under themes/ when theme is also set.
+// The ref is resolved when the commit is built, themeMoved reports when it
+// has moved on since
+func (c *Cache) loadTheme(tree *git.Tree, site *Site) (*theme, error) {
+	if site.Theme == "" && site.ThemeRef == "" {
+		return nil, nil
+	}
+
+	t := &theme{Tree: tree}
+
+	if site.ThemeRef != "" {
+		id, err := c.themeCommitID(site.ThemeRef)
+		if err != nil {
+			return nil, fmt.Errorf("Theme ref %q could not be read: %s", site.ThemeRef, err)
+		}
+
+		commit, err := c.Repo.GetCommit(id)
+		if err != nil {
+			return nil, fmt.Errorf("Theme ref %q could not be read: %s", site.ThemeRef, err)
+		}
+
+		t.Tree = git.NewTree(c.Repo, commit.TreeId())
+		t.Path = site.ThemeRef + ":"
+		t.Ref = site.ThemeRef
+		t.Commit = id
+	}
+
+	if site.Theme != "" {
+		dir, err := themeDir(site.Theme)
+		if err != nil {
+			return nil, err
+		}
+
+		entry, err := t.Tree.GetTreeEntryByPath(dir)
+		if err != nil || !entry.IsDir() {
+			return nil, fmt.Errorf("Theme %q not found in %s", site.Theme, t.Path+dir)
+		}
+
+		t.Tree = git.NewTree(c.Repo, entry.Id)
+		t.Path += dir + "/"
+	}
+
+	return t, nil
+}
+
+// themeDir is the directory of a theme in its tree, names are a single
+// directory under themes/
+func themeDir(name string) (string, error) {
+	dir := path.Join(ThemesDir, name)
+	if strings.Contains(name, "/") || path.Dir(dir) != ThemesDir {
+		return "", fmt.Errorf("Invalid theme name %q", name)
+	}
+	return dir, nil
+}
+
+// themeCommitID gets the commit id of a theme ref, refs without a refs/
+// prefix are branch names
+func (c *Cache) themeCommitID(ref string) (string, error) {
+	switch {
+	case strings.HasPrefix(ref, "refs/heads/"):
+		return c.Repo.GetCommitIdOfBranch(strings.TrimPrefix(ref, "refs/heads/"))
+	case strings.HasPrefix(ref, "refs/tags/"):
+		return c.Repo.GetCommitIdOfTag(strings.TrimPrefix(ref, "refs/tags/"))
+	}
+	return c.Repo.GetCommitIdOfBranch(ref)
+}
+
+// themeMoved checks if the theme ref of a built commit points at a different
+// commit than when it was built, so previews pick up theme changes
+func (c *Cache) themeMoved(t *theme) bool {
+	if t == nil || t.Ref == "" {
+		return false
+	}
+
+	id, err := c.themeCommitID(t.Ref)
+	return err != nil || id != t.Commit
+}
+
+// fileTree finds files by path, it is implemented by *git.Tree and layers
+type fileTree interface {
+	GetBlobByPath(path string) (*git.Blob, error)
+}
+
+// layers are trees searched in order for a file, the content tree goes
+// first so its files take precedence over the theme
+type layers []fileTree
+
+// GetBlobByPath gets a file from the first tree that has it
+func (l layers) GetBlobByPath(path string) (*git.Blob, error) {
+	for _, tree := range l {
+		if blob, err := tree.GetBlobByPath(path); err == nil {
+			return blob, nil
+		}
+	}
+	return nil, git.ErrNotExist
+}
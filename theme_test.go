@@ -0,0 +1,98 @@
+package blog
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gogits/git"
+)
+
+func TestThemeDir(t *testing.T) {
+	tests := []struct {
+		name string
+		dir  string
+	}{
+		{"plain", "themes/plain"},
+		{"dark-mode", "themes/dark-mode"},
+		{"", ""},
+		{".", ""},
+		{"..", ""},
+		{"../x", ""},
+		{"a/b", ""},
+		{"a/", ""},
+		{"/a", ""},
+	}
+
+	for _, test := range tests {
+		dir, err := themeDir(test.name)
+
+		if test.dir == "" {
+			if err == nil || !strings.Contains(err.Error(), "Invalid theme name") {
+				t.Errorf("%q: expected an invalid theme name, got %q and %v", test.name, dir, err)
+			}
+			continue
+		}
+
+		if err != nil || dir != test.dir {
+			t.Errorf("%q: expected %q, got %q and %v", test.name, test.dir, dir, err)
+		}
+	}
+}
+
+// testTree is a fileTree of blobs named by path
+type testTree map[string]string
+
+func (tree testTree) GetBlobByPath(path string) (*git.Blob, error) {
+	id, ok := tree[path]
+	if !ok {
+		return nil, git.ErrNotExist
+	}
+
+	sha, err := git.NewIdFromString(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &git.Blob{TreeEntry: &git.TreeEntry{Id: sha}}, nil
+}
+
+func TestLayersGetBlobByPath(t *testing.T) {
+	content := testTree{
+		"templates/header.tpl": strings.Repeat("1", 40),
+		"style.css":            strings.Repeat("2", 40),
+	}
+	theme := testTree{
+		"templates/header.tpl": strings.Repeat("a", 40),
+		"templates/footer.tpl": strings.Repeat("b", 40),
+	}
+
+	tests := []struct {
+		path string
+		id   string
+	}{
+		{"templates/header.tpl", strings.Repeat("1", 40)},
+		{"style.css", strings.Repeat("2", 40)},
+		{"templates/footer.tpl", strings.Repeat("b", 40)},
+		{"missing.tpl", ""},
+	}
+
+	files := layers{content, theme}
+	for _, test := range tests {
+		blob, err := files.GetBlobByPath(test.path)
+
+		if test.id == "" {
+			if err != git.ErrNotExist {
+				t.Errorf("%s: expected git.ErrNotExist, got %v", test.path, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", test.path, err)
+			continue
+		}
+		if id := blob.Id.String(); id != test.id {
+			t.Errorf("%s: expected blob %s, got %s", test.path, test.id, id)
+		}
+	}
+}
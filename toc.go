@@ -0,0 +1,75 @@
+package blog
+
+import (
+	"bytes"
+	"html"
+	"html/template"
+	"strconv"
+)
+
+// TOCEntry is a heading in a table of contents
+type TOCEntry struct {
+	Level    int
+	ID       string
+	Title    string
+	Children TOC
+}
+
+// TOC is a nested table of contents
+type TOC []*TOCEntry
+
+// NewTOC nests a flat list of headings by level, a heading becomes a child of
+// the closest preceding heading with a lower level
+func NewTOC(headings []*TOCEntry) TOC {
+	var toc TOC
+	var stack []*TOCEntry
+
+	for _, heading := range headings {
+		entry := &TOCEntry{
+			Level: heading.Level,
+			ID:    heading.ID,
+			Title: heading.Title,
+		}
+
+		for len(stack) > 0 && stack[len(stack)-1].Level >= entry.Level {
+			stack = stack[:len(stack)-1]
+		}
+
+		if len(stack) == 0 {
+			toc = append(toc, entry)
+		} else {
+			parent := stack[len(stack)-1]
+			parent.Children = append(parent.Children, entry)
+		}
+
+		stack = append(stack, entry)
+	}
+
+	return toc
+}
+
+// HTML renders the table of contents as nested lists linking to each heading
+func (t TOC) HTML() template.HTML {
+	if len(t) == 0 {
+		return ""
+	}
+
+	var buffer bytes.Buffer
+	t.render(&buffer)
+	return template.HTML(buffer.String())
+}
+
+func (t TOC) render(out *bytes.Buffer) {
+	out.WriteString(`<ul class="toc">`)
+	for _, entry := range t {
+		out.WriteString(`<li class="toc__entry toc__entry--h` + strconv.Itoa(entry.Level) + `">`)
+		out.WriteString(`<a href="#` + html.EscapeString(entry.ID) + `">`)
+		out.WriteString(html.EscapeString(entry.Title))
+		out.WriteString(`</a>`)
+		if len(entry.Children) > 0 {
+			entry.Children.render(out)
+		}
+		out.WriteString(`</li>`)
+	}
+	out.WriteString(`</ul>`)
+}
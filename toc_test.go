@@ -0,0 +1,136 @@
+package blog
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// tocString formats a table of contents as ids with children in brackets
+func tocString(toc TOC) string {
+	var parts []string
+	for _, entry := range toc {
+		s := entry.ID
+		if len(entry.Children) > 0 {
+			s += "[" + tocString(entry.Children) + "]"
+		}
+		parts = append(parts, s)
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestNewTOC(t *testing.T) {
+	tests := []struct {
+		name   string
+		levels []int
+		toc    string
+	}{
+		{"empty", nil, ""},
+		{"flat", []int{2, 2, 2}, "1 2 3"},
+		{"nested", []int{1, 2, 3, 2}, "1[2[3] 4]"},
+		{"skipped level", []int{2, 4, 2}, "1[2] 3"},
+		{"skipped level back up", []int{2, 4, 3, 2}, "1[2 3] 4"},
+		{"starts deep", []int{3, 1, 2}, "1 2[3]"},
+		{"deep to shallow", []int{4, 3, 2, 1}, "1 2 3 4"},
+	}
+
+	for _, test := range tests {
+		var headings []*TOCEntry
+		for i, level := range test.levels {
+			headings = append(headings, &TOCEntry{Level: level, ID: strconv.Itoa(i + 1)})
+		}
+
+		if toc := tocString(NewTOC(headings)); toc != test.toc {
+			t.Errorf("%s: expected %q, got %q", test.name, test.toc, toc)
+		}
+	}
+}
+
+func TestHeadingIDs(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		ids    []string
+		titles []string
+	}{
+		{
+			name:   "slug",
+			source: "# Hello, World!",
+			ids:    []string{"hello-world"},
+			titles: []string{"Hello, World!"},
+		},
+		{
+			name:   "duplicates",
+			source: "## Setup\n\n## Setup\n\n## Setup",
+			ids:    []string{"setup", "setup-1", "setup-2"},
+			titles: []string{"Setup", "Setup", "Setup"},
+		},
+		{
+			name:   "unicode",
+			source: "## Привет мир\n\n## 日本語",
+			ids:    []string{"привет-мир", "日本語"},
+			titles: []string{"Привет мир", "日本語"},
+		},
+		{
+			name:   "explicit id",
+			source: "## Install it {#install}\n\n## Install",
+			ids:    []string{"install", "install-1"},
+			titles: []string{"Install it", "Install"},
+		},
+		{
+			name:   "no letters",
+			source: "## ???\n\n## ---!",
+			ids:    []string{"section", "section-1"},
+		},
+		{
+			name:   "markup is stripped from the title",
+			source: "## The `go` *tool* &amp; you",
+			ids:    []string{"the-go-tool-you"},
+			titles: []string{"The go tool & you"},
+		},
+	}
+
+	for _, test := range tests {
+		ctx := &RenderContext{Site: DefaultSite()}
+		data, err := renderMarkdown(ctx, []byte(test.source))
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", test.name, err)
+			continue
+		}
+
+		if len(ctx.Headings) != len(test.ids) {
+			t.Errorf("%s: expected %d headings, got %d", test.name, len(test.ids), len(ctx.Headings))
+			continue
+		}
+
+		for i, heading := range ctx.Headings {
+			if heading.ID != test.ids[i] {
+				t.Errorf("%s: expected heading %d to have id %q, got %q", test.name, i, test.ids[i], heading.ID)
+			}
+			if test.titles != nil && heading.Title != test.titles[i] {
+				t.Errorf("%s: expected heading %d to have title %q, got %q", test.name, i, test.titles[i], heading.Title)
+			}
+			if !strings.Contains(string(data), ` id="`+heading.ID+`">`) {
+				t.Errorf("%s: expected an anchor %q in %q", test.name, heading.ID, data)
+			}
+		}
+	}
+}
+
+func TestTOCHTML(t *testing.T) {
+	toc := NewTOC([]*TOCEntry{
+		{Level: 2, ID: "a", Title: "A & B"},
+		{Level: 3, ID: "b\"", Title: "<b>"},
+	})
+
+	expected := `<ul class="toc"><li class="toc__entry toc__entry--h2"><a href="#a">A &amp; B</a>` +
+		`<ul class="toc"><li class="toc__entry toc__entry--h3"><a href="#b&#34;">&lt;b&gt;</a></li></ul></li></ul>`
+
+	if html := string(toc.HTML()); html != expected {
+		t.Errorf("expected %q, got %q", expected, html)
+	}
+
+	if html := TOC(nil).HTML(); html != "" {
+		t.Errorf("expected an empty table of contents to render nothing, got %q", html)
+	}
+}
@@ -0,0 +1,149 @@
+package blog
+
+import (
+	"bytes"
+	"html"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	xhtml "golang.org/x/net/html"
+)
+
+// wikiLinkPattern matches [[name]] and [[name|label]]
+var wikiLinkPattern = regexp.MustCompile(`\[\[([^\[\]|]+)(?:\|([^\[\]]+))?\]\]`)
+
+// wikiLinkSkip are the elements whose text is never searched for wiki links
+var wikiLinkSkip = map[string]bool{
+	"a":      true,
+	"code":   true,
+	"pre":    true,
+	"script": true,
+	"style":  true,
+}
+
+// expandWikiLinks replaces [[name]] and [[name|label]] in the text of rendered
+// HTML with links to articles, the article title is used when no label is
+// given. Links to missing articles are marked as broken
+func expandWikiLinks(data []byte, resolve func(string) (*Article, bool)) []byte {
+	if !bytes.Contains(data, []byte("[[")) {
+		return data
+	}
+
+	var out bytes.Buffer
+	skip := 0
+
+	tokenizer := xhtml.NewTokenizer(bytes.NewReader(data))
+	for {
+		tt := tokenizer.Next()
+		if tt == xhtml.ErrorToken {
+			return out.Bytes()
+		}
+
+		raw := tokenizer.Raw()
+
+		switch tt {
+		case xhtml.StartTagToken:
+			if name, _ := tokenizer.TagName(); wikiLinkSkip[string(name)] {
+				skip++
+			}
+		case xhtml.EndTagToken:
+			if name, _ := tokenizer.TagName(); wikiLinkSkip[string(name)] && skip > 0 {
+				skip--
+			}
+		case xhtml.TextToken:
+			if skip == 0 {
+				raw = wikiLinkPattern.ReplaceAllFunc(raw, func(match []byte) []byte {
+					return wikiLink(match, resolve)
+				})
+			}
+		}
+
+		out.Write(raw)
+	}
+}
+
+func wikiLink(match []byte, resolve func(string) (*Article, bool)) []byte {
+	groups := wikiLinkPattern.FindSubmatch(match)
+	target := strings.TrimSpace(html.UnescapeString(string(groups[1])))
+	label := strings.TrimSpace(string(groups[2]))
+
+	article, ok := resolve(target)
+	if !ok {
+		if label == "" {
+			label = html.EscapeString(target)
+		}
+		href := baseURLPlaceholder + "article/" + (&url.URL{Path: target}).EscapedPath() + "/"
+		return []byte(`<a class="wikilink wikilink--broken" href="` + href + `" title="Article not found">` + label + `</a>`)
+	}
+
+	if label == "" {
+		label = html.EscapeString(article.Title)
+	}
+	href := baseURLPlaceholder + (&url.URL{Path: article.Link()}).EscapedPath()
+	return []byte(`<a class="wikilink" href="` + href + `">` + label + `</a>`)
+}
+
+// resolveWikiLink finds the article a wiki link points at, the target is
+// tried as a full article name, then relative to the section of the linking
+// article, then against article titles and the ends of article names
+func resolveWikiLink(articles map[string]*Article, section string, target string) (*Article, bool) {
+	target = strings.Trim(target, "/")
+	if ext := path.Ext(target); articleExtensions[ext] {
+		target = strings.TrimSuffix(target, ext)
+	}
+
+	if article, ok := articles[target]; ok {
+		return article, true
+	}
+
+	if article, ok := articles[path.Join(section, target)]; ok {
+		return article, true
+	}
+
+	// Several articles may share a title or end in the same path, the first
+	// by name wins so links are stable between builds
+	var match *Article
+	slug := Slugify(target)
+	for _, article := range articles {
+		if Slugify(article.Title) != slug && !strings.HasSuffix(article.Name, "/"+target) {
+			continue
+		}
+		if match == nil || article.Name < match.Name {
+			match = article
+		}
+	}
+
+	return match, match != nil
+}
+
+// linkedArticles finds the articles linked to from rendered HTML
+func linkedArticles(data []byte, articles map[string]*Article) []*Article {
+	var linked []*Article
+	seen := make(map[string]bool)
+
+	for _, ref := range findLinks(data) {
+		if !strings.HasPrefix(ref.Link, baseURLPlaceholder+"article/") {
+			continue
+		}
+
+		u, err := url.Parse(ref.Link)
+		if err != nil {
+			continue
+		}
+
+		name := strings.Trim(strings.TrimPrefix(u.Path, baseURLPlaceholder+"article/"), "/")
+		for n := name; n != "." && n != ""; n = path.Dir(n) {
+			if article, ok := articles[n]; ok {
+				if !seen[n] {
+					seen[n] = true
+					linked = append(linked, article)
+				}
+				break
+			}
+		}
+	}
+
+	return linked
+}
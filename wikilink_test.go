@@ -0,0 +1,98 @@
+package blog
+
+import "testing"
+
+func testWikiArticles() map[string]*Article {
+	return map[string]*Article{
+		"hello-world":   {Name: "hello-world", Title: "Hello World"},
+		"go/generics":   {Name: "go/generics", Title: "Generics in Go"},
+		"go/tips/one":   {Name: "go/tips/one", Title: "Tip One"},
+		"rust/tips/one": {Name: "rust/tips/one", Title: "Another Tip"},
+	}
+}
+
+func TestExpandWikiLinks(t *testing.T) {
+	articles := testWikiArticles()
+	resolve := func(target string) (*Article, bool) {
+		return resolveWikiLink(articles, "go", target)
+	}
+
+	tests := []struct {
+		name string
+		data string
+		out  string
+	}{
+		{
+			name: "no links",
+			data: "<p>[single] brackets</p>",
+			out:  "<p>[single] brackets</p>",
+		},
+		{
+			name: "title is the label",
+			data: "<p>See [[hello-world]].</p>",
+			out:  `<p>See <a class="wikilink" href="/~base~/article/hello-world/">Hello World</a>.</p>`,
+		},
+		{
+			name: "label",
+			data: "<p>[[generics|the generics post]]</p>",
+			out:  `<p><a class="wikilink" href="/~base~/article/go/generics/">the generics post</a></p>`,
+		},
+		{
+			name: "missing",
+			data: "<p>[[missing page]]</p>",
+			out:  `<p><a class="wikilink wikilink--broken" href="/~base~/article/missing%20page/" title="Article not found">missing page</a></p>`,
+		},
+		{
+			name: "missing with label",
+			data: "<p>[[missing|Soon]]</p>",
+			out:  `<p><a class="wikilink wikilink--broken" href="/~base~/article/missing/" title="Article not found">Soon</a></p>`,
+		},
+		{
+			name: "escaped target",
+			data: "<p>[[a &amp; b]]</p>",
+			out:  `<p><a class="wikilink wikilink--broken" href="/~base~/article/a%20&%20b/" title="Article not found">a &amp; b</a></p>`,
+		},
+		{
+			name: "code and links are skipped",
+			data: "<p><code>[[hello-world]]</code> <a href=\"x\">[[hello-world]]</a></p>",
+			out:  "<p><code>[[hello-world]]</code> <a href=\"x\">[[hello-world]]</a></p>",
+		},
+	}
+
+	for _, test := range tests {
+		if out := string(expandWikiLinks([]byte(test.data), resolve)); out != test.out {
+			t.Errorf("%s: expected %q, got %q", test.name, test.out, out)
+		}
+	}
+}
+
+func TestResolveWikiLink(t *testing.T) {
+	articles := testWikiArticles()
+
+	tests := []struct {
+		section string
+		target  string
+		name    string
+	}{
+		{"", "hello-world", "hello-world"},
+		{"", "/hello-world.md", "hello-world"},
+		{"go", "generics", "go/generics"},
+		{"", "generics in go", "go/generics"},
+		{"", "Tip One", "go/tips/one"},
+		{"", "tips/one", "go/tips/one"},
+		{"rust", "tips/one", "rust/tips/one"},
+		{"", "missing", ""},
+	}
+
+	for _, test := range tests {
+		article, ok := resolveWikiLink(articles, test.section, test.target)
+
+		name := ""
+		if ok {
+			name = article.Name
+		}
+		if name != test.name {
+			t.Errorf("%q from %q: expected %q, got %q", test.target, test.section, test.name, name)
+		}
+	}
+}